@@ -3,34 +3,104 @@ package miri
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 )
 
 type Album struct {
 	Results struct {
-		Data struct {
-			Title               string `json:"ALB_TITLE"`
-			Artist              string `json:"ART_NAME"`
-			OriginalReleaseDate string `json:"ORIGINAL_RELEASE_DATE"`
-			PhysicalReleaseDate string `json:"PHYSICAL_RELEASE_DATE"`
-			Label               string `json:"LABEL_NAME"`
-			ProducerLine        string `json:"PRODUCER_LINE"`
-			Copyright           string `json:"COPYRIGHT"`
-			Duration            string `json:"DURATION"`
-		} `json:"DATA"`
+		Data  AlbumData `json:"DATA"`
 		Songs struct {
 			Data []*Song `json:"data"`
 		} `json:"SONGS"`
 	} `json:"results"`
 }
 
-func (a *Album) String() string {
-	duration, err := strconv.Atoi(a.Results.Data.Duration)
-	if err != nil {
-		duration = 0
+type AlbumData struct {
+	Title        string `json:"ALB_TITLE"`
+	Artist       string `json:"ART_NAME"`
+	Label        string `json:"LABEL_NAME"`
+	ProducerLine string `json:"PRODUCER_LINE"`
+	Copyright    string `json:"COPYRIGHT"`
+
+	// Deprecated: use OriginalRelease.
+	OriginalReleaseDate string `json:"ORIGINAL_RELEASE_DATE"`
+	// Deprecated: use PhysicalRelease.
+	PhysicalReleaseDate string `json:"PHYSICAL_RELEASE_DATE"`
+	// Deprecated: use DurationSeconds.
+	Duration string `json:"DURATION"`
+
+	OriginalRelease time.Time `json:"-"`
+	PhysicalRelease time.Time `json:"-"`
+	DurationSeconds int       `json:"-"`
+
+	ID           string       `json:"ALB_ID"`
+	UPC          string       `json:"UPC"`
+	Cover        string       `json:"ALB_PICTURE"`
+	Contributors Contributors `json:"ALB_CONTRIBUTORS"`
+	GenreID      int          `json:"GENRE_ID"`
+	DiskCount    int          `json:"NUMBER_DISK"`
+	TrackCount   int          `json:"NUMBER_TRACK"`
+	Explicit     bool         `json:"EXPLICIT"`
+	RecordType   string       `json:"RECORD_TYPE"` // one of the Release* constants
+}
+
+func (d *AlbumData) UnmarshalJSON(data []byte) error {
+	type Alias AlbumData
+	aux := struct {
+		*Alias
+		Duration   flexString `json:"DURATION"`
+		GenreID    flexInt    `json:"GENRE_ID"`
+		DiskCount  flexInt    `json:"NUMBER_DISK"`
+		TrackCount flexInt    `json:"NUMBER_TRACK"`
+		Explicit   *flexBool  `json:"EXPLICIT"`
+		Type       *flexInt   `json:"TYPE"`
+		Content    struct {
+			Status flexInt `json:"EXPLICIT_LYRICS_STATUS"`
+		} `json:"EXPLICIT_ALBUM_CONTENT"`
+	}{Alias: (*Alias)(d)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
 	}
 
+	d.Duration = string(aux.Duration)
+	duration, _ := parseNumber(d.Duration)
+	d.DurationSeconds = int(duration)
+	d.OriginalRelease = parseDate(d.OriginalReleaseDate)
+	d.PhysicalRelease = parseDate(d.PhysicalReleaseDate)
+	d.GenreID = int(aux.GenreID)
+	d.DiskCount = int(aux.DiskCount)
+	d.TrackCount = int(aux.TrackCount)
+
+	// the gateway reports explicit content as a status, 1 and 4 being explicit and partially explicit
+	if aux.Explicit != nil {
+		d.Explicit = bool(*aux.Explicit)
+	} else {
+		d.Explicit = aux.Content.Status == 1 || aux.Content.Status == 4
+	}
+
+	if aux.Type != nil {
+		d.RecordType = releaseTypes[int(*aux.Type)]
+	}
+
+	return nil
+}
+
+// ReleaseDate returns the original release date of the album, falling back to the physical one.
+func (d *AlbumData) ReleaseDate() time.Time {
+	if !d.OriginalRelease.IsZero() {
+		return d.OriginalRelease
+	}
+
+	return d.PhysicalRelease
+}
+
+// DurationTime returns the total duration of the album.
+func (d *AlbumData) DurationTime() time.Duration {
+	return time.Duration(d.DurationSeconds) * time.Second
+}
+
+func (a *Album) String() string {
 	return fmt.Sprintf(
 		`================= [ Album Info ] =================
 Title:    %s
@@ -41,7 +111,7 @@ Duration: %s
 		a.Results.Data.Title,
 		a.Results.Data.Artist,
 		len(a.Results.Songs.Data),
-		time.Duration(duration)*time.Second,
+		a.Results.Data.DurationTime(),
 	)
 }
 
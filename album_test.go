@@ -0,0 +1,45 @@
+package miri
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAlbumDataUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		duration time.Duration
+		released string // empty for the zero time
+	}{
+		{"strings", `{"DURATION":"3600","ORIGINAL_RELEASE_DATE":"2001-03-07","PHYSICAL_RELEASE_DATE":"2001-03-12"}`, time.Hour, "2001-03-07"},
+		{"fractional duration", `{"DURATION":"224.0"}`, 224 * time.Second, ""},
+		{"number", `{"DURATION":224,"ORIGINAL_RELEASE_DATE":"0000-00-00","PHYSICAL_RELEASE_DATE":"2001-03-12"}`, 224 * time.Second, "2001-03-12"},
+		{"missing", `{"DURATION":null}`, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d AlbumData
+			if err := json.Unmarshal([]byte(tt.data), &d); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := d.DurationTime(); got != tt.duration {
+				t.Errorf("DurationTime() = %v, want %v", got, tt.duration)
+			}
+			if got := time.Duration(d.DurationSeconds) * time.Second; got != tt.duration {
+				t.Errorf("DurationSeconds = %d, want %v", d.DurationSeconds, tt.duration)
+			}
+
+			date := d.ReleaseDate()
+			switch {
+			case tt.released == "" && !date.IsZero():
+				t.Errorf("ReleaseDate() = %v, want the zero time", date)
+			case tt.released != "" && date.Format(time.DateOnly) != tt.released:
+				t.Errorf("ReleaseDate() = %v, want %s", date, tt.released)
+			}
+		})
+	}
+}
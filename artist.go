@@ -3,7 +3,6 @@ package miri
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -11,7 +10,9 @@ import (
 type Artist struct {
 	Results struct {
 		Data struct {
-			Name string `json:"ART_NAME"`
+			ID      string `json:"ART_ID"`
+			Name    string `json:"ART_NAME"`
+			Picture string `json:"ART_PICTURE"`
 		} `json:"DATA"`
 		Songs struct {
 			Data []*Song `json:"data"`
@@ -48,13 +49,10 @@ func (a *Artist) String() string {
 		limit = count
 	}
 
-	totalSec := 0
+	var totalDuration time.Duration
 	for _, s := range tracks {
-		if d, err := strconv.Atoi(s.Duration); err == nil {
-			totalSec += d
-		}
+		totalDuration += s.DurationTime()
 	}
-	totalDuration := time.Duration(totalSec) * time.Second
 
 	var b strings.Builder
 	fmt.Fprintf(&b, "============= [ Artist Info ] =============\n")
@@ -0,0 +1,186 @@
+package miri
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	authTokenURL      = "https://api.deezer.com/auth/token"
+	oauthAuthorizeURL = "https://connect.deezer.com/oauth/auth.php"
+	oauthTokenURL     = "https://connect.deezer.com/oauth/access_token.php"
+	getArlURL         = "https://www.deezer.com/ajax/gw-light.php?method=user.getArl&input=3&api_version=1.0&api_token=null"
+)
+
+type authTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Expires     int    `json:"expires"`
+	Error       struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// LoginWithEmail logs in through Deezer's mobile auth endpoint and returns an arl cookie.
+// The app ID and secret must belong to an app allowed to use the password grant.
+func LoginWithEmail(ctx context.Context, email, password, appID, appSecret string) (string, error) {
+	client, err := newSessionClient()
+	if err != nil {
+		return "", err
+	}
+
+	passwordHash := md5Hex(password)
+	p := url.Values{}
+	p.Set("app_id", appID)
+	p.Set("login", email)
+	p.Set("password", passwordHash)
+	p.Set("hash", md5Hex(appID+email+passwordHash+appSecret))
+
+	token, err := getAccessToken(ctx, client, authTokenURL+"?"+p.Encode())
+	if err != nil {
+		return "", fmt.Errorf("failed to log in: %w", err)
+	}
+
+	// any authenticated call to the API sets the session cookies on deezer.com
+	req, err := http.NewRequestWithContext(ctx, "GET", deezerAPIBase+"user/me", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	err = checkUserResponse(resp)
+	resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "POST", getArlURL, strings.NewReader("{}"))
+	if err != nil {
+		return "", err
+	}
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var res struct {
+		Results string `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("failed to decode arl response: %w", err)
+	}
+
+	if res.Results == "" {
+		return "", fmt.Errorf("no arl returned")
+	}
+
+	return res.Results, nil
+}
+
+// OAuthURL returns the page where users authorize an app to access their account.
+// After authorizing, they are redirected to redirectURI with a code to pass to ExchangeOAuthCode.
+func OAuthURL(appID, redirectURI string, perms ...string) string {
+	p := url.Values{}
+	p.Set("app_id", appID)
+	p.Set("redirect_uri", redirectURI)
+	p.Set("perms", strings.Join(perms, ","))
+
+	return oauthAuthorizeURL + "?" + p.Encode()
+}
+
+// ExchangeOAuthCode trades an OAuth code for an access token for the public API.
+func ExchangeOAuthCode(ctx context.Context, appID, appSecret, code string) (string, error) {
+	p := url.Values{}
+	p.Set("app_id", appID)
+	p.Set("secret", appSecret)
+	p.Set("code", code)
+	p.Set("output", "json")
+
+	token, err := getAccessToken(ctx, http.DefaultClient, oauthTokenURL+"?"+p.Encode())
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange OAuth code: %w", err)
+	}
+
+	return token, nil
+}
+
+func getAccessToken(ctx context.Context, client *http.Client, tokenURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var res authTokenResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+
+	if res.Error.Message != "" {
+		return "", fmt.Errorf("%s", res.Error.Message)
+	}
+	if res.AccessToken == "" || res.AccessToken == "undefined" {
+		return "", fmt.Errorf("no access token returned")
+	}
+
+	return res.AccessToken, nil
+}
+
+// checkUserResponse checks the answer of the public API to user/me, which reports
+// invalid tokens with an error object rather than a status code.
+func checkUserResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var res struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("failed to decode user: %w", err)
+	}
+	if res.Error.Message != "" {
+		return fmt.Errorf("%s: %s", res.Error.Type, res.Error.Message)
+	}
+
+	return nil
+}
+
+func md5Hex(s string) string {
+	hash := md5.Sum([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,240 @@
+package miri
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheTempPrefix = ".tmp-"
+
+// diskCache stores decrypted media on disk, evicting the least recently used files
+// once maxSize is exceeded. Concurrent requests for the same key share a single fill.
+type diskCache struct {
+	dir     string
+	maxSize int64 // unbounded if zero
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+	flights map[string]*cacheFlight
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+type cacheFlight struct {
+	done chan struct{}
+	err  error
+}
+
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	d := &diskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		flights: make(map[string]*cacheFlight),
+	}
+
+	var infos []os.FileInfo
+	for _, f := range files {
+		if !f.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(f.Name(), cacheTempPrefix) {
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	// the modification time is bumped on every hit, so it doubles as the access time
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		d.entries[info.Name()] = d.lru.PushBack(&cacheEntry{key: info.Name(), size: info.Size()})
+		d.size += info.Size()
+	}
+
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+
+	return d, nil
+}
+
+func cacheKey(songID, format string) string {
+	return songID + "_" + format + extension(format)
+}
+
+// lookup returns the cached file for key, or nil on a miss. It does not wait for pending fills.
+func (d *diskCache) lookup(key string) (*os.File, error) {
+	d.mu.Lock()
+	el, ok := d.entries[key]
+	if !ok {
+		d.mu.Unlock()
+		return nil, nil
+	}
+	d.lru.MoveToFront(el)
+	d.mu.Unlock()
+
+	path := filepath.Join(d.dir, key)
+	f, err := os.Open(path)
+	if err == nil {
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// removed behind our back
+	d.mu.Lock()
+	d.remove(el)
+	d.mu.Unlock()
+	return nil, nil
+}
+
+// open returns the cached file for key, calling fill to populate it on a miss.
+func (d *diskCache) open(ctx context.Context, key string, fill func(w io.Writer) error) (*os.File, error) {
+	for {
+		f, err := d.lookup(key)
+		if f != nil || err != nil {
+			return f, err
+		}
+
+		d.mu.Lock()
+		if _, ok := d.entries[key]; ok {
+			// filled in the meantime
+			d.mu.Unlock()
+			continue
+		}
+
+		if fl, ok := d.flights[key]; ok {
+			d.mu.Unlock()
+			select {
+			case <-fl.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if fl.err != nil && (!isContextError(fl.err) || ctx.Err() != nil) {
+				return nil, fl.err
+			}
+			// the fill was done or cancelled by its own caller, so look again and possibly take over
+			continue
+		}
+
+		fl := &cacheFlight{done: make(chan struct{})}
+		d.flights[key] = fl
+		d.mu.Unlock()
+
+		fl.err = d.fill(key, fill)
+
+		d.mu.Lock()
+		delete(d.flights, key)
+		d.mu.Unlock()
+		close(fl.done)
+
+		if fl.err != nil {
+			return nil, fl.err
+		}
+	}
+}
+
+// fill writes a new entry through a temporary file, so that partial downloads are never served.
+func (d *diskCache) fill(key string, fill func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(d.dir, cacheTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := fill(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(d.dir, key)); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[key]; ok {
+		d.remove(el)
+	}
+	d.entries[key] = d.lru.PushFront(&cacheEntry{key: key, size: info.Size()})
+	d.size += info.Size()
+	d.evict()
+
+	return nil
+}
+
+// evict removes the least recently used entries until the cache fits, always keeping the newest one.
+// The caller must hold d.mu.
+func (d *diskCache) evict() {
+	if d.maxSize <= 0 {
+		return
+	}
+
+	for d.size > d.maxSize && d.lru.Len() > 1 {
+		el := d.lru.Back()
+		os.Remove(filepath.Join(d.dir, el.Value.(*cacheEntry).key))
+		d.remove(el)
+	}
+}
+
+// remove forgets an entry, unless it was already removed. The caller must hold d.mu.
+func (d *diskCache) remove(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	if d.entries[entry.key] != el {
+		return
+	}
+
+	d.lru.Remove(el)
+	delete(d.entries, entry.key)
+	d.size -= entry.size
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,114 @@
+package miri
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestDiskCacheWaiterSurvivesCancelledFill(t *testing.T) {
+	d, err := newDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	firstCtx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := d.open(firstCtx, "key", func(w io.Writer) error {
+			close(started)
+			<-firstCtx.Done()
+			return firstCtx.Err()
+		})
+		if err == nil {
+			t.Error("expected the cancelled fill to fail")
+		}
+	}()
+
+	<-started
+	result := make(chan error, 1)
+	go func() {
+		f, err := d.open(context.Background(), "key", func(w io.Writer) error {
+			_, err := io.WriteString(w, "data")
+			return err
+		})
+		if err == nil {
+			f.Close()
+		}
+		result <- err
+	}()
+
+	cancel()
+	wg.Wait()
+
+	if err := <-result; err != nil {
+		t.Fatalf("waiter failed with the error of the cancelled fill: %v", err)
+	}
+}
+
+func TestDiskCacheRemoveTwice(t *testing.T) {
+	d, err := newDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill := func(w io.Writer) error {
+		_, err := io.WriteString(w, "data")
+		return err
+	}
+	f, err := d.open(context.Background(), "key", fill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	el := d.entries["key"]
+	d.remove(el)
+	d.remove(el)
+	if d.size != 0 {
+		t.Fatalf("size is %d after removing the only entry twice", d.size)
+	}
+}
+
+func TestStreamTrackFromCache(t *testing.T) {
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return nil, nil
+	})
+	c.appConfig.Quality = "mp3_320"
+
+	var err error
+	c.cache, err = newDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"MP3_128", "MP3_320", "FLAC"} {
+		f, err := c.cache.open(context.Background(), cacheKey("42", format), func(w io.Writer) error {
+			_, err := io.WriteString(w, format)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	var buf bytes.Buffer
+	if err := c.StreamTrackByID(context.Background(), 42, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// FLAC is better, but not allowed by the quality
+	if buf.String() != "MP3_320" {
+		t.Fatalf("got %q, want the cached MP3_320", buf.String())
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("cache hit made %d gateway calls", len(*calls))
+	}
+}
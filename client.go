@@ -4,121 +4,294 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
+	"time"
 )
 
+const defaultMediaURL = "https://media.deezer.com/v1/get_url"
+
+// trackTokenMargin leaves time to start a download with a cached track token before it expires.
+const trackTokenMargin = 5 * time.Minute
+
 func New(ctx context.Context, appConfig *Config) (*Client, error) {
-	session, err := authenticate(ctx, appConfig.ArlCookie)
+	arlCookie := appConfig.ArlCookie
+	if arlCookie == "" {
+		var err error
+		arlCookie, err = LoginWithEmail(ctx, appConfig.Email, appConfig.Password, appConfig.AppID, appConfig.AppSecret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := authenticate(ctx, arlCookie)
 	if err != nil {
 		return nil, fmt.Errorf("failed to authenticate: %w", err)
 	}
 
+	return newVerifiedClient(ctx, appConfig, session)
+}
+
+// NewFromSession creates a client from a session saved by a previous one, without authenticating.
+// The session is validated lazily: it is refreshed with its arl cookie only when Deezer rejects it,
+// or right away if it is known to be expired.
+func NewFromSession(ctx context.Context, appConfig *Config, session *Session) (*Client, error) {
+	if session == nil || session.HttpClient == nil {
+		return nil, fmt.Errorf("invalid session")
+	}
+
+	if session.Expired() {
+		refreshed, err := authenticate(ctx, session.ArlCookie)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh session: %w", err)
+		}
+		session = refreshed
+	}
+
+	return newVerifiedClient(ctx, appConfig, session)
+}
+
+// newVerifiedClient creates a client and verifies its secret key if the config asks for it.
+func newVerifiedClient(ctx context.Context, appConfig *Config, session *Session) (*Client, error) {
+	c, err := newClient(appConfig, session)
+	if err != nil {
+		return c, err
+	}
+
+	if appConfig.VerifyKey {
+		if err := c.VerifySecretKey(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func newClient(appConfig *Config, session *Session) (*Client, error) {
 	c := &Client{appConfig: appConfig, session: session}
 
+	if appConfig.CacheDir != "" {
+		var err error
+		c.cache, err = newDiskCache(appConfig.CacheDir, appConfig.CacheMaxSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	q := appConfig.Quality
 
-	if !c.session.Premium && (q == "mp3_320" || q == "flac") {
+	if !session.Premium && (q == "mp3_320" || q == "flac") {
 		return c, fmt.Errorf("premium account required for '%s' quality", q)
 	}
 
 	return c, nil
 }
 
-func (c *Client) fetchResource(ctx context.Context, resource Resource, id int) error {
-	resourceID := strconv.Itoa(id)
-	payload := map[string]interface{}{
-		"nb":     10000,
-		"start":  0,
-		"lang":   "en",
-		"tab":    0,
-		"tags":   true,
-		"header": true,
-	}
-	switch r := resource.(type) {
-	case *Playlist:
-		payload["playlist_id"] = resourceID
-	case *Album:
-		payload["alb_id"] = resourceID
-	case *Artist:
-		payload["art_id"] = resourceID
-	case *Track:
-		payload["sng_id"] = resourceID
-	default:
-		return fmt.Errorf("unsupported resource type: %T", r)
-	}
+// Session returns the current session, which can be saved and passed to NewFromSession later.
+func (c *Client) Session() *Session {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
 
-	jsonData, err := json.Marshal(payload)
+	return c.session
+}
+
+// VerifySecretKey downloads the first chunk of a known track and checks that the configured secret key decrypts it.
+func (c *Client) VerifySecretKey(ctx context.Context) error {
+	song, err := c.GetSongFromTrackID(ctx, verificationTrackID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get verification track: %w", err)
 	}
 
-	url := fmt.Sprintf("https://www.deezer.com/ajax/gw-light.php?method=deezer.page%s&input=3&api_version=1.0&api_token=%s", resource.GetType(), c.session.APIToken)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	media, err := c.fetchMedia(ctx, song, "mp3_128")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to fetch media: %w", err)
 	}
 
-	resp, err := c.session.HttpClient.Do(req)
+	resp, err := c.openMedia(ctx, media, 0, chunkSize-1)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get media stream: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	firstChunk := make([]byte, chunkSize)
+	if _, err := io.ReadFull(resp.Body, firstChunk); err != nil {
+		return fmt.Errorf("failed to read media: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	return VerifySecretKey(c.appConfig.SecretKey, song.ID, firstChunk)
+}
+
+// User returns information about the account of the current session.
+func (c *Client) User() UserInfo {
+	return c.Session().User
+}
+
+// withSession calls fn with the current session. If Deezer rejects the session,
+// it authenticates again with the same arl cookie and retries once.
+func (c *Client) withSession(ctx context.Context, fn func(s *Session) error) error {
+	session := c.Session()
+
+	err := fn(session)
+	if !errors.Is(err, ErrSessionExpired) {
 		return err
 	}
 
-	switch {
-	case strings.Contains(string(body), `"DATA_ERROR":"playlist::getData"`):
-		return fmt.Errorf("invalid playlist ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"album::getData"`):
-		return fmt.Errorf("invalid album ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"artist::getData"`):
-		return fmt.Errorf("invalid artist ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"song::getData"`):
-		return fmt.Errorf("invalid track ID")
+	c.sessionMu.Lock()
+	if c.session == session {
+		refreshed, authErr := authenticate(ctx, session.ArlCookie)
+		if authErr != nil {
+			c.sessionMu.Unlock()
+			return fmt.Errorf("%w: failed to refresh session: %w", ErrSessionExpired, authErr)
+		}
+		c.session = refreshed
+	}
+	session = c.session
+	c.sessionMu.Unlock()
+
+	return fn(session)
+}
+
+// GetResource fetches a resource by kind (see NewResource) and ID.
+func (c *Client) GetResource(ctx context.Context, kind string, id int) (Resource, error) {
+	resource, err := NewResource(kind)
+	if err != nil {
+		return nil, err
 	}
 
-	if strings.Contains(string(body), `"results":{}`) {
-		return fmt.Errorf("unexpected response")
+	if err := c.FetchResource(ctx, resource, id); err != nil {
+		return nil, err
 	}
 
-	return resource.Unmarshal(body)
+	return resource, nil
 }
 
-func (c *Client) fetchMedia(ctx context.Context, song *Song, quality string) (*Media, error) {
-	var formats string
+// FetchResource populates resource with the data of the given ID.
+func (c *Client) FetchResource(ctx context.Context, resource Resource, id int) error {
+	if err := c.fetchResource(ctx, resource, id); err != nil {
+		return fmt.Errorf("failed to fetch resource: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) fetchResource(ctx context.Context, resource Resource, id int) error {
+	cache := c.appConfig.MetadataCache
+	if cache != nil {
+		if data, ok := cache.Get(resource.GetType(), id); ok {
+			if err := resource.Unmarshal(data); err != nil {
+				return err
+			}
+			// entries written without token expiries may hold stale track tokens
+			if trackTokenTTL(time.Hour, resource.GetSongs()) > 0 {
+				return nil
+			}
+		}
+	}
+
+	var songs []*Song
+	if show, ok := resource.(*Show); ok {
+		var episodes []*EpisodeData
+		for episode, err := range c.Episodes(ctx, show, id) {
+			if err != nil {
+				return err
+			}
+			episodes = append(episodes, episode)
+		}
+		show.Results.Episodes.Data = episodes
+	} else {
+		for song, err := range c.Songs(ctx, resource, id) {
+			if err != nil {
+				return err
+			}
+			songs = append(songs, song)
+		}
+		resource.SetSongs(songs)
+	}
+
+	if cache != nil {
+		if ttl := trackTokenTTL(c.appConfig.metadataTTL(resource.GetType()), songs); ttl > 0 {
+			data, err := json.Marshal(resource)
+			if err != nil {
+				return err
+			}
+			cache.Set(resource.GetType(), id, data, ttl)
+		}
+	}
+
+	return nil
+}
+
+// trackTokenTTL caps ttl so that cached songs expire before their track tokens do.
+// Songs with a track token of unknown expiry are never cached.
+func trackTokenTTL(ttl time.Duration, songs []*Song) time.Duration {
+	for _, song := range songs {
+		switch {
+		case song.TrackTokenExpire > 0:
+			expiry := time.Unix(song.TrackTokenExpire, 0).Add(-trackTokenMargin)
+			ttl = min(ttl, time.Until(expiry))
+		case song.TrackToken != "":
+			return 0
+		}
+	}
+
+	return ttl
+}
+
+// GetMedia negotiates the media of a song in the configured quality, falling back to lower ones.
+func (c *Client) GetMedia(ctx context.Context, song *Song) (*Media, error) {
+	return c.fetchMedia(ctx, song, c.appConfig.Quality)
+}
+
+func (c *Client) fetchMedia(ctx context.Context, song *Song, quality string) (media *Media, err error) {
+	err = c.withSession(ctx, func(session *Session) error {
+		media, err = c.fetchSessionMedia(ctx, session, song, quality)
+		return err
+	})
 
+	return media, err
+}
+
+// qualityFormats returns the formats allowed by a quality, best first.
+func qualityFormats(quality string) []string {
 	switch quality {
-	case "mp3_128":
-		formats = `[{"cipher":"BF_CBC_STRIPE","format":"MP3_128"}]`
-	case "mp3_320":
-		formats = `[{"cipher":"BF_CBC_STRIPE","format":"MP3_320"},{"cipher":"BF_CBC_STRIPE","format":"MP3_128"}]`
 	case "flac":
-		formats = `[{"cipher":"BF_CBC_STRIPE","format":"FLAC"},{"cipher":"BF_CBC_STRIPE","format":"MP3_320"},{"cipher":"BF_CBC_STRIPE","format":"MP3_128"}]`
+		return []string{"FLAC", "MP3_320", "MP3_128"}
+	case "mp3_320":
+		return []string{"MP3_320", "MP3_128"}
+	default:
+		return []string{"MP3_128"}
+	}
+}
+
+func (c *Client) fetchSessionMedia(ctx context.Context, session *Session, song *Song, quality string) (*Media, error) {
+	var formats []string
+	for _, format := range qualityFormats(quality) {
+		formats = append(formats, fmt.Sprintf(`{"cipher":"BF_CBC_STRIPE","format":"%s"}`, format))
+	}
+
+	reqBody := fmt.Sprintf(`{"license_token":"%s","media":[{"type":"FULL","formats":[%s]}],"track_tokens":["%s"]}`, session.LicenseToken, strings.Join(formats, ","), song.TrackToken)
+	mediaURL := c.appConfig.MediaURL
+	if mediaURL == "" {
+		mediaURL = defaultMediaURL
 	}
 
-	reqBody := fmt.Sprintf(`{"license_token":"%s","media":[{"type":"FULL","formats":%s}],"track_tokens":["%s"]}`, c.session.LicenseToken, formats, song.TrackToken)
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://media.deezer.com/v1/get_url", bytes.NewBuffer([]byte(reqBody)))
+	req, err := http.NewRequestWithContext(ctx, "POST", mediaURL, bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.session.HttpClient.Do(req)
+	resp, err := session.HttpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrQuotaExceeded
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -136,14 +309,17 @@ func (c *Client) fetchMedia(ctx context.Context, song *Song, quality string) (*M
 
 	if len(media.Errors) > 0 {
 		if media.Errors[0].Code == 1000 {
-			return nil, fmt.Errorf("invalid license token")
+			return nil, fmt.Errorf("%w: invalid license token", ErrSessionExpired)
 		}
 
 		return nil, fmt.Errorf("%s", media.Errors[0].Message)
 	}
 
 	if len(media.Data) > 0 && len(media.Data[0].Errors) > 0 {
-		if media.Data[0].Errors[0].Code == 2002 {
+		switch media.Data[0].Errors[0].Code {
+		case 2000:
+			return nil, ErrGeoBlocked
+		case 2002:
 			return nil, fmt.Errorf("invalid track token")
 		}
 
@@ -158,13 +334,31 @@ func (c *Client) fetchMedia(ctx context.Context, song *Song, quality string) (*M
 }
 
 func (c *Client) GetMediaStream(ctx context.Context, media *Media, songID string) (io.ReadCloser, error) {
+	resp, err := c.openMedia(ctx, media, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// openMedia requests the encrypted media from the CDN, from byte start to byte end included (-1 for the end of the file).
+// The caller must check the status code, since the CDN may ignore the Range header and reply with the whole file.
+func (c *Client) openMedia(ctx context.Context, media *Media, start, end int64) (*http.Response, error) {
 	url := media.GetURL()
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	streamingClient := *c.session.HttpClient
+	switch {
+	case end >= 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	case start > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+	}
+
+	streamingClient := *c.Session().HttpClient
 	streamingClient.Timeout = 0
 
 	resp, err := streamingClient.Do(req)
@@ -172,9 +366,17 @@ func (c *Client) GetMediaStream(ctx context.Context, media *Media, songID string
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, &RangeError{Size: parseContentRangeSize(resp.Header.Get("Content-Range"))}
+	case http.StatusTooManyRequests:
+		resp.Body.Close()
+		return nil, ErrQuotaExceeded
+	default:
+		resp.Body.Close()
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
-
-	return resp.Body, nil
 }
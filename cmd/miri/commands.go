@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/birabittoh/miri"
+)
+
+func runSearch(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := flags.Uint64("limit", 10, "maximum number of results")
+	asJSON := flags.Bool("json", false, "print results as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return errors.New("usage: miri search [-limit n] [-json] <query>")
+	}
+
+	opt := miri.SearchOptions{Limit: *limit, Query: strings.Join(flags.Args(), " ")}
+	results, err := miri.SearchTracks(ctx, opt)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(results)
+	}
+	return printSearchResults(results)
+}
+
+func runInfo(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	kind := flags.String("type", "track", "resource type of bare IDs (track, album, playlist, artist, episode, show)")
+	asJSON := flags.Bool("json", false, "print the resource as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: miri info [-type kind] [-json] <id|url>")
+	}
+
+	resKind, id, err := parseRef(ctx, flags.Arg(0), *kind)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(ctx, cfgPath, "")
+	if err != nil {
+		return err
+	}
+
+	resource, err := c.GetResource(ctx, resKind, id)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(miri.NewResourceView(resource))
+	}
+
+	fmt.Println(resource)
+	return printSongs(os.Stdout, resource.GetSongs())
+}
+
+func runDownload(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("download", flag.ExitOnError)
+	kind := flags.String("type", "track", "resource type of bare IDs (track, album, playlist, artist, episode, show)")
+	quality := flags.String("quality", "", "audio quality (mp3_128, mp3_320, flac)")
+	outDir := flags.String("o", ".", "output directory")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: miri download [-type kind] [-quality q] [-o dir] <id|url>")
+	}
+
+	resKind, id, err := parseRef(ctx, flags.Arg(0), *kind)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(ctx, cfgPath, *quality)
+	if err != nil {
+		return err
+	}
+
+	resource, err := c.GetResource(ctx, resKind, id)
+	if err != nil {
+		return err
+	}
+
+	dir := *outDir
+	if resKind != "track" && resKind != "episode" {
+		dir = filepath.Join(dir, miri.SanitizeFilename(resource.GetTitle()))
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, song := range resource.GetSongs() {
+		entry, err := c.SaveSong(ctx, song, dir, nil)
+		if err != nil {
+			return fmt.Errorf("failed to download song %s: %w", song.ID, err)
+		}
+		fmt.Printf("%s  %s\n", entry.SHA256, filepath.Join(dir, entry.Path))
+	}
+
+	return nil
+}
+
+func runSync(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	quality := flags.String("quality", "", "audio quality (mp3_128, mp3_320, flac)")
+	outDir := flags.String("o", ".", "output directory")
+	prune := flags.Bool("prune", false, "delete songs removed from the playlist")
+	upgrade := flags.Bool("upgrade", false, "download again songs available in a better format")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: miri sync [-quality q] [-o dir] [-prune] [-upgrade] <playlist id|url>")
+	}
+
+	kind, id, err := parseRef(ctx, flags.Arg(0), "playlist")
+	if err != nil {
+		return err
+	}
+	if kind != "playlist" {
+		return fmt.Errorf("only playlists can be synced, got %s", kind)
+	}
+
+	c, err := newClient(ctx, cfgPath, *quality)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.SyncPlaylist(ctx, id, miri.SyncOptions{Dir: *outDir, Prune: *prune, Upgrade: *upgrade})
+	if report != nil {
+		for _, entry := range report.Added {
+			fmt.Printf("+ %s\n", entry.Path)
+		}
+		for _, entry := range report.Upgraded {
+			fmt.Printf("^ %s\n", entry.Path)
+		}
+		for _, entry := range report.Removed {
+			fmt.Printf("- %s\n", entry.Path)
+		}
+		for id, err := range report.Failed {
+			fmt.Fprintf(os.Stderr, "failed to sync song %s: %v\n", id, err)
+		}
+		fmt.Printf("%d added, %d upgraded, %d removed, %d unchanged, %d failed\n",
+			len(report.Added), len(report.Upgraded), len(report.Removed), report.Unchanged, len(report.Failed))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d songs failed to sync", len(report.Failed))
+	}
+	return nil
+}
+
+func runLyrics(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("lyrics", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: miri lyrics <id|url>")
+	}
+
+	kind, id, err := parseRef(ctx, flags.Arg(0), "track")
+	if err != nil {
+		return err
+	}
+	if kind != "track" {
+		return fmt.Errorf("lyrics are only available for tracks, got %s", kind)
+	}
+
+	c, err := newClient(ctx, cfgPath, "")
+	if err != nil {
+		return err
+	}
+
+	song, err := c.GetSongFromTrackID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	lyrics, err := song.Lyrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(lyrics)
+	return nil
+}
+
+func runDecrypt(ctx context.Context, cfgPath string, args []string) error {
+	flags := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		return errors.New("usage: miri decrypt <song-id> <in|-> <out|->")
+	}
+
+	fc, err := readFileConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if path := flags.Arg(1); path != "-" {
+		in, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+
+	if flags.Arg(2) == "-" {
+		return miri.DecryptFile(fc.SecretKey, flags.Arg(0), in, os.Stdout)
+	}
+
+	out, err := os.Create(flags.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	if err := miri.DecryptFile(fc.SecretKey, flags.Arg(0), in, out); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/birabittoh/miri"
+)
+
+type fileConfig struct {
+	ArlCookie string `json:"arl_cookie"`
+	SecretKey string `json:"secret_key"`
+	Quality   string `json:"quality"`
+
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	AppID     string `json:"app_id"`
+	AppSecret string `json:"app_secret"`
+
+	CacheDir     string `json:"cache_dir"`
+	CacheMaxSize int64  `json:"cache_max_size"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "miri", "config.json")
+}
+
+// readFileConfig reads the config file, if any, and overrides it with the environment.
+func readFileConfig(path string) (*fileConfig, error) {
+	var fc fileConfig
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &fc); err != nil {
+				return nil, fmt.Errorf("failed to parse config file: %w", err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	if v := os.Getenv("ARL_COOKIE"); v != "" {
+		fc.ArlCookie = v
+	}
+	if v := os.Getenv("SECRET_KEY"); v != "" {
+		fc.SecretKey = v
+	}
+
+	return &fc, nil
+}
+
+func loadConfig(path, quality string) (*miri.Config, error) {
+	fc, err := readFileConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &miri.Config{
+		ArlCookie:    fc.ArlCookie,
+		SecretKey:    fc.SecretKey,
+		Quality:      "mp3_128",
+		Email:        fc.Email,
+		Password:     fc.Password,
+		AppID:        fc.AppID,
+		AppSecret:    fc.AppSecret,
+		CacheDir:     fc.CacheDir,
+		CacheMaxSize: fc.CacheMaxSize,
+	}
+
+	if quality == "" {
+		quality = fc.Quality
+	}
+	if quality != "" {
+		cfg.Quality = quality
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func newClient(ctx context.Context, cfgPath, quality string) (*miri.Client, error) {
+	cfg, err := loadConfig(cfgPath, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	return miri.New(ctx, cfg)
+}
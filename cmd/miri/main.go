@@ -0,0 +1,65 @@
+// Command miri searches, inspects and downloads music from Deezer.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+const usage = `usage: miri [-config path] <command> [flags] [args]
+
+commands:
+  search    search tracks by query
+  info      show a track, album, playlist, artist, episode or show
+  download  download a track, album, playlist, artist, episode or show
+  sync      download the new songs of a playlist into a directory
+  lyrics    print the lyrics of a track
+  decrypt   decrypt a file downloaded from the CDN
+
+Resources can be given as deezer.com URLs or as IDs. Bare IDs are
+tracks unless -type is set.
+
+The arl cookie and secret key are read from ARL_COOKIE and SECRET_KEY,
+falling back to the config file.
+`
+
+type command func(ctx context.Context, cfgPath string, args []string) error
+
+var commands = map[string]command{
+	"search":   runSearch,
+	"info":     runInfo,
+	"download": runDownload,
+	"sync":     runSync,
+	"lyrics":   runLyrics,
+	"decrypt":  runDecrypt,
+}
+
+func main() {
+	flags := flag.NewFlagSet("miri", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	cfgPath := flags.String("config", defaultConfigPath(), "path to the config file")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, *cfgPath, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "miri: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/birabittoh/miri"
+)
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printSearchResults(results []miri.SongResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tARTIST\tALBUM\tDURATION")
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.ID, r.Title, r.Artist.Name, r.Album.Title, time.Duration(r.Duration)*time.Second)
+	}
+	return w.Flush()
+}
+
+func printSongs(out io.Writer, songs []*miri.Song) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tID\tTITLE\tARTIST\tDURATION")
+	for i, s := range songs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, s.ID, s.GetTitle(), s.Artist, s.DurationTime())
+	}
+	return w.Flush()
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/birabittoh/miri"
+)
+
+// parseRef accepts a Deezer URL or a bare ID of the given kind.
+func parseRef(ctx context.Context, arg, kind string) (string, int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return kind, id, nil
+	}
+
+	return miri.ParseURL(ctx, arg)
+}
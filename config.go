@@ -21,6 +21,26 @@ type Config struct {
 	SecretKey string
 	Quality   string
 	Timeout   time.Duration
+
+	Email       string // used to log in when ArlCookie is empty
+	Password    string
+	AppID       string // Deezer app used for email/password and OAuth logins
+	AppSecret   string
+	AccessToken string // OAuth access token sent to the public API, see ExchangeOAuthCode
+
+	VerifyKey bool // check SecretKey against a known track when creating the client
+
+	GatewayURL string // endpoint of the private API, defaults to Deezer's
+	MediaURL   string // endpoint of the media API, defaults to Deezer's
+
+	CacheDir     string // directory of the download cache, disabled if empty
+	CacheMaxSize int64  // maximum size of the download cache in bytes, unbounded if zero
+
+	MetadataCache MetadataCache // cache of fetched resources, disabled if nil
+	AlbumTTL      time.Duration // how long albums are cached, defaults to a week
+	PlaylistTTL   time.Duration // how long playlists are cached, defaults to ten minutes
+	ArtistTTL     time.Duration // how long artists are cached, defaults to a day
+	TrackTTL      time.Duration // how long tracks are cached, defaults to a week
 }
 
 func NewConfig(arlCookie, secretKey string) (*Config, error) {
@@ -39,9 +59,34 @@ func NewConfig(arlCookie, secretKey string) (*Config, error) {
 	return config, nil
 }
 
+// NewConfigWithCredentials creates a config that logs in with an email and a password instead of an arl cookie.
+func NewConfigWithCredentials(email, password, appID, appSecret, secretKey string) (*Config, error) {
+	config := &Config{
+		SecretKey: secretKey,
+		Quality:   defaultQuality,
+		Timeout:   defaultTimeout,
+		Email:     email,
+		Password:  password,
+		AppID:     appID,
+		AppSecret: appSecret,
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 func (c *Config) Validate() error {
 	if c.ArlCookie == "" {
-		return fmt.Errorf("arl_cookie is not set")
+		if c.Email == "" || c.Password == "" {
+			return fmt.Errorf("arl_cookie is not set")
+		}
+		if c.AppID == "" || c.AppSecret == "" {
+			return fmt.Errorf("app_id and app_secret are required to log in with email and password")
+		}
 	}
 	if c.SecretKey == "" {
 		return fmt.Errorf("secret_key is not set")
@@ -54,6 +99,10 @@ func (c *Config) Validate() error {
 		c.Timeout = defaultTimeout
 	}
 
+	if c.CacheMaxSize < 0 {
+		return fmt.Errorf("cache_max_size cannot be negative")
+	}
+
 	_, ok := validQualities[c.Quality]
 	if !ok {
 		return fmt.Errorf("invalid quality: %s", c.Quality)
@@ -61,3 +110,24 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// metadataTTL returns how long a resource of the given type can be cached.
+func (c *Config) metadataTTL(resourceType string) time.Duration {
+	var ttl time.Duration
+	switch resourceType {
+	case "Album":
+		ttl = c.AlbumTTL
+	case "Playlist":
+		ttl = c.PlaylistTTL
+	case "Artist":
+		ttl = c.ArtistTTL
+	case "Track":
+		ttl = c.TrackTTL
+	}
+
+	if ttl <= 0 {
+		ttl = defaultMetadataTTLs[resourceType]
+	}
+
+	return ttl
+}
@@ -3,7 +3,9 @@ package miri
 import (
 	"crypto/cipher"
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"io"
 
 	"golang.org/x/crypto/blowfish"
 )
@@ -34,3 +36,110 @@ func decrypt(data, key []byte) ([]byte, error) {
 
 	return decrypted, nil
 }
+
+// decryptingReader decrypts a BF_CBC_STRIPE stream: every third full chunk,
+// starting from the first one, is encrypted on its own.
+type decryptingReader struct {
+	r       io.Reader
+	key     []byte
+	chunk   int // stripe index of the next chunk
+	buf     []byte
+	pending []byte // decrypted bytes not yet returned
+	err     error
+}
+
+// NewDecryptingReader returns a reader that decrypts r with key as it is read.
+// The key is the per-song Blowfish key derived from the secret key and the song ID.
+func NewDecryptingReader(r io.Reader, key []byte) io.Reader {
+	return newDecryptingReader(r, key, 0)
+}
+
+// newDecryptingReader is like NewDecryptingReader for a stream that starts at the given chunk index.
+func newDecryptingReader(r io.Reader, key []byte, firstChunk int) *decryptingReader {
+	return &decryptingReader{
+		r:     r,
+		key:   key,
+		chunk: firstChunk,
+		buf:   make([]byte, chunkSize),
+	}
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		n, err := io.ReadFull(d.r, d.buf)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			d.err = err
+		}
+
+		data := d.buf[:n]
+		if d.chunk%3 == 0 && n == chunkSize {
+			decrypted, err := decrypt(data, d.key)
+			if err != nil {
+				d.err = err
+				return 0, err
+			}
+			copy(data, decrypted)
+		}
+
+		d.chunk++
+		d.pending = data
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// DecryptFile decrypts media previously downloaded from the CDN, without needing a session.
+func DecryptFile(secretKey, songID string, in io.Reader, out io.Writer) error {
+	if len(secretKey) != 16 {
+		return fmt.Errorf("secret_key must be 16 bytes long")
+	}
+
+	_, err := io.Copy(out, NewDecryptingReader(in, getKey(secretKey, songID)))
+	return err
+}
+
+// ErrBadSecretKey is returned when the secret key does not decrypt media correctly.
+var ErrBadSecretKey = errors.New("bad secret key")
+
+// VerifySecretKey decrypts the first chunk of a song's encrypted media
+// and checks that it starts like an MP3 or FLAC file.
+func VerifySecretKey(secretKey, songID string, firstChunk []byte) error {
+	if len(secretKey) != 16 {
+		return fmt.Errorf("secret_key must be 16 bytes long")
+	}
+	if len(firstChunk) < chunkSize {
+		return fmt.Errorf("need %d bytes of media, got %d", chunkSize, len(firstChunk))
+	}
+
+	decrypted, err := decrypt(firstChunk[:chunkSize], getKey(secretKey, songID))
+	if err != nil {
+		return err
+	}
+
+	if !hasAudioMagic(decrypted) {
+		return ErrBadSecretKey
+	}
+
+	return nil
+}
+
+// hasAudioMagic reports whether data starts with an ID3 tag, an MP3 frame sync or a FLAC marker.
+func hasAudioMagic(data []byte) bool {
+	switch {
+	case len(data) < 4:
+		return false
+	case string(data[:3]) == "ID3", string(data[:4]) == "fLaC":
+		return true
+	default:
+		return data[0] == 0xff && data[1]&0xe0 == 0xe0
+	}
+}
@@ -0,0 +1,171 @@
+package miri
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+const testSecretKey = "0123456789abcdef"
+
+// encryptStripes encrypts every third full chunk of plain, like the CDN does.
+func encryptStripes(t *testing.T, plain, key []byte) []byte {
+	t.Helper()
+
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Clone(plain)
+	for i := 0; (i+1)*chunkSize <= len(out); i += 3 {
+		chunk := out[i*chunkSize : (i+1)*chunkSize]
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(chunk, chunk)
+	}
+
+	return out
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+// readAllWith reads r with a buffer of the given size.
+func readAllWith(r io.Reader, size int) ([]byte, error) {
+	var out []byte
+	buf := make([]byte, size)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+	}
+}
+
+func TestDecryptingReader(t *testing.T) {
+	key := getKey(testSecretKey, "3135556")
+
+	tests := []struct {
+		name     string
+		size     int
+		readSize int
+	}{
+		{"empty", 0, 4096},
+		{"short tail", 7*chunkSize + 123, 4096},
+		{"shorter than a chunk", 1000, 4096},
+		{"exact chunks", 6 * chunkSize, 4096},
+		{"read size 1", 4*chunkSize + 5, 1},
+		{"read size 7", 4*chunkSize + 5, 7},
+		{"read size 4097", 10*chunkSize + 2047, 4097},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := randomBytes(tt.size)
+			r := NewDecryptingReader(bytes.NewReader(encryptStripes(t, plain, key)), key)
+
+			got, err := readAllWith(r, tt.readSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("decrypted %d bytes, not matching the %d plain ones", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestDecryptingReaderOffset(t *testing.T) {
+	key := getKey(testSecretKey, "3135556")
+	plain := randomBytes(9*chunkSize + 100)
+	encrypted := encryptStripes(t, plain, key)
+
+	for _, first := range []int{1, 2, 3, 4} {
+		r := newDecryptingReader(bytes.NewReader(encrypted[first*chunkSize:]), key, first)
+
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plain[first*chunkSize:]) {
+			t.Fatalf("first chunk %d: decrypted output does not match", first)
+		}
+	}
+}
+
+// failingReader returns the data of r, then fails instead of returning io.EOF.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, f.err
+	}
+	return n, err
+}
+
+func TestDecryptingReaderError(t *testing.T) {
+	key := getKey(testSecretKey, "3135556")
+	plain := randomBytes(4 * chunkSize)
+	encrypted := encryptStripes(t, plain, key)
+	errBroken := errors.New("connection reset")
+
+	tests := []struct {
+		name     string
+		cut      int
+		readSize int
+	}{
+		{"mid plain chunk", chunkSize + 1000, 4096},
+		{"chunk boundary", 2 * chunkSize, 7},
+		{"mid first chunk", 1000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &failingReader{r: bytes.NewReader(encrypted[:tt.cut]), err: errBroken}
+
+			got, err := readAllWith(NewDecryptingReader(source, key), tt.readSize)
+			if !errors.Is(err, errBroken) {
+				t.Fatalf("got error %v, want %v", err, errBroken)
+			}
+
+			// full chunks are decrypted, while a partial one is passed through as it was read
+			complete := tt.cut - tt.cut%chunkSize
+			want := append(bytes.Clone(plain[:complete]), encrypted[complete:tt.cut]...)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got %d bytes before the error, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestDecryptFile(t *testing.T) {
+	plain := randomBytes(3*chunkSize + 17)
+	encrypted := encryptStripes(t, plain, getKey(testSecretKey, "42"))
+
+	var out bytes.Buffer
+	if err := DecryptFile(testSecretKey, "42", bytes.NewReader(encrypted), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Fatal("decrypted file does not match")
+	}
+
+	if err := DecryptFile("short", "42", bytes.NewReader(encrypted), io.Discard); err == nil {
+		t.Fatal("expected an error for a short secret key")
+	}
+}
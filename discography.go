@@ -0,0 +1,130 @@
+package miri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Release types, as returned in Release.Type.
+const (
+	ReleaseAlbum       = "album"
+	ReleaseSingle      = "single"
+	ReleaseEP          = "ep"
+	ReleaseCompilation = "compilation"
+)
+
+// roleFeatured is the role of an artist who only appears on a release.
+const roleFeatured = 5
+
+var releaseTypes = map[int]string{
+	0: ReleaseSingle,
+	1: ReleaseAlbum,
+	2: ReleaseCompilation,
+	3: ReleaseEP,
+}
+
+// DiscographyFilter selects the releases returned by GetArtistDiscography.
+type DiscographyFilter struct {
+	Types           []string // release types to include, all of them if empty
+	IncludeFeatured bool     // include releases the artist only appears on
+	WithSongs       bool     // fetch the songs of every release
+}
+
+// Release is an entry of an artist's discography.
+type Release struct {
+	ID          string  `json:"ALB_ID"`
+	Title       string  `json:"ALB_TITLE"`
+	Artist      string  `json:"ART_NAME"`
+	Cover       string  `json:"ALB_PICTURE"`
+	ReleaseDate string  `json:"ORIGINAL_RELEASE_DATE"`
+	Type        string  `json:"-"` // empty if the gateway did not send it
+	Featured    bool    `json:"-"` // the artist only appears on this release
+	TrackCount  int     `json:"-"`
+	Songs       []*Song `json:"-"` // only set when DiscographyFilter.WithSongs is true
+}
+
+func (r *Release) UnmarshalJSON(data []byte) error {
+	type Alias Release
+	aux := struct {
+		*Alias
+		PhysicalReleaseDate string   `json:"PHYSICAL_RELEASE_DATE"`
+		Type                *flexInt `json:"TYPE"`
+		Role                flexInt  `json:"ROLE_ID"`
+		TrackCount          flexInt  `json:"NUMBER_TRACK"`
+	}{Alias: (*Alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if r.ReleaseDate == "" {
+		r.ReleaseDate = aux.PhysicalReleaseDate
+	}
+	// a missing type must not be mistaken for 0, which is a single
+	if aux.Type != nil {
+		r.Type = releaseTypes[int(*aux.Type)]
+	}
+	r.Featured = aux.Role == roleFeatured
+	r.TrackCount = int(aux.TrackCount)
+
+	return nil
+}
+
+// GetArtistDiscography lists the releases of an artist, page by page through album.getDiscography.
+func (c *Client) GetArtistDiscography(ctx context.Context, artistID int, filter DiscographyFilter) ([]*Release, error) {
+	roles := []int{0}
+	if filter.IncludeFeatured {
+		roles = append(roles, roleFeatured)
+	}
+
+	var releases []*Release
+	for start := 0; ; {
+		payload := map[string]any{
+			"art_id":           strconv.Itoa(artistID),
+			"discography_mode": "all",
+			"filter_role_id":   roles,
+			"nb":               pageSize,
+			"nb_songs":         0,
+			"start":            start,
+		}
+
+		var page struct {
+			Data  []*Release `json:"data"`
+			Total int        `json:"total"`
+		}
+		if err := c.callGateway(ctx, "album.getDiscography", payload, &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch discography: %w", err)
+		}
+
+		for _, r := range page.Data {
+			if len(filter.Types) == 0 || slices.Contains(filter.Types, r.Type) {
+				releases = append(releases, r)
+			}
+		}
+
+		start += len(page.Data)
+		if len(page.Data) == 0 || start >= page.Total {
+			break
+		}
+	}
+
+	if filter.WithSongs {
+		for _, r := range releases {
+			albumID, err := strconv.Atoi(r.ID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid album ID: %s", r.ID)
+			}
+
+			album := &Album{}
+			if err := c.FetchResource(ctx, album, albumID); err != nil {
+				return nil, err
+			}
+			r.Songs = album.GetSongs()
+		}
+	}
+
+	return releases, nil
+}
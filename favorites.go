@@ -0,0 +1,128 @@
+package miri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// favoriteMethods maps a kind of favorite to its gateway methods and ID parameter.
+var favoriteMethods = map[string]struct {
+	add, remove, idKey string
+}{
+	"song":   {"favorite_song.add", "favorite_song.remove", "SNG_ID"},
+	"album":  {"album.addFavorite", "album.deleteFavorite", "ALB_ID"},
+	"artist": {"artist.addFavorite", "artist.deleteFavorite", "ART_ID"},
+}
+
+// LoveSong adds a song to the loved tracks of the current user. Loving a loved song is not an error.
+func (c *Client) LoveSong(ctx context.Context, songID int) error {
+	return c.setFavorite(ctx, "song", songID, true)
+}
+
+// UnloveSong removes a song from the loved tracks of the current user. Unloving a song that is not loved is not an error.
+func (c *Client) UnloveSong(ctx context.Context, songID int) error {
+	return c.setFavorite(ctx, "song", songID, false)
+}
+
+// FavoriteAlbum saves an album to the library of the current user.
+func (c *Client) FavoriteAlbum(ctx context.Context, albumID int) error {
+	return c.setFavorite(ctx, "album", albumID, true)
+}
+
+// UnfavoriteAlbum removes an album from the library of the current user.
+func (c *Client) UnfavoriteAlbum(ctx context.Context, albumID int) error {
+	return c.setFavorite(ctx, "album", albumID, false)
+}
+
+// FollowArtist follows an artist with the current user.
+func (c *Client) FollowArtist(ctx context.Context, artistID int) error {
+	return c.setFavorite(ctx, "artist", artistID, true)
+}
+
+// UnfollowArtist stops following an artist with the current user.
+func (c *Client) UnfollowArtist(ctx context.Context, artistID int) error {
+	return c.setFavorite(ctx, "artist", artistID, false)
+}
+
+// LoveSongs is the batch variant of LoveSong. It goes through every song and joins the errors.
+func (c *Client) LoveSongs(ctx context.Context, songIDs ...int) error {
+	return c.setFavorites(ctx, "song", songIDs, true)
+}
+
+// UnloveSongs is the batch variant of UnloveSong.
+func (c *Client) UnloveSongs(ctx context.Context, songIDs ...int) error {
+	return c.setFavorites(ctx, "song", songIDs, false)
+}
+
+// FavoriteAlbums is the batch variant of FavoriteAlbum.
+func (c *Client) FavoriteAlbums(ctx context.Context, albumIDs ...int) error {
+	return c.setFavorites(ctx, "album", albumIDs, true)
+}
+
+// UnfavoriteAlbums is the batch variant of UnfavoriteAlbum.
+func (c *Client) UnfavoriteAlbums(ctx context.Context, albumIDs ...int) error {
+	return c.setFavorites(ctx, "album", albumIDs, false)
+}
+
+// FollowArtists is the batch variant of FollowArtist.
+func (c *Client) FollowArtists(ctx context.Context, artistIDs ...int) error {
+	return c.setFavorites(ctx, "artist", artistIDs, true)
+}
+
+// UnfollowArtists is the batch variant of UnfollowArtist.
+func (c *Client) UnfollowArtists(ctx context.Context, artistIDs ...int) error {
+	return c.setFavorites(ctx, "artist", artistIDs, false)
+}
+
+func (c *Client) setFavorites(ctx context.Context, kind string, ids []int, add bool) error {
+	var errs []error
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if err := c.setFavorite(ctx, kind, id, add); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Client) setFavorite(ctx context.Context, kind string, id int, add bool) error {
+	methods := favoriteMethods[kind]
+	method, action := methods.remove, "remove"
+	if add {
+		method, action = methods.add, "add"
+	}
+
+	payload := map[string]any{methods.idKey: strconv.Itoa(id)}
+	err := c.callGateway(ctx, method, payload, nil)
+	if err != nil && !isUnchangedFavorite(err, add) {
+		return fmt.Errorf("failed to %s favorite %s %d: %w", action, kind, id, err)
+	}
+
+	return nil
+}
+
+// Gateway error codes for changes that leave the favorites as they were,
+// which are successes for idempotent calls.
+const (
+	favoriteExistsCode    = "ERROR_DATA_EXISTS"
+	favoriteNotExistsCode = "ERROR_DATA_NOT_EXISTS"
+)
+
+// isUnchangedFavorite reports whether the gateway refused to add a favorite that was
+// already there, or to remove one that was not. Any other error, such as an unknown ID, is a failure.
+func isUnchangedFavorite(err error, add bool) bool {
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) {
+		return false
+	}
+
+	if add {
+		return gwErr.Code == favoriteExistsCode
+	}
+	return gwErr.Code == favoriteNotExistsCode
+}
@@ -0,0 +1,86 @@
+package miri
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFavoriteIdempotence(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    func(c *Client) error
+		method  string
+		errs    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "love",
+			call:   func(c *Client) error { return c.LoveSong(t.Context(), 3135556) },
+			method: "favorite_song.add",
+		},
+		{
+			name:   "love a loved song",
+			call:   func(c *Client) error { return c.LoveSong(t.Context(), 3135556) },
+			method: "favorite_song.add",
+			errs:   map[string]string{"ERROR_DATA_EXISTS": "Song already in favorites"},
+		},
+		{
+			name:   "unfavorite an album that is not saved",
+			call:   func(c *Client) error { return c.UnfavoriteAlbum(t.Context(), 302127) },
+			method: "album.deleteFavorite",
+			errs:   map[string]string{"ERROR_DATA_NOT_EXISTS": "Album not in favorites"},
+		},
+		{
+			name:    "love an unknown song",
+			call:    func(c *Client) error { return c.LoveSong(t.Context(), 999999999) },
+			method:  "favorite_song.add",
+			errs:    map[string]string{"ERROR_DATA_NOT_FOUND": "Song does not exist"},
+			wantErr: true,
+		},
+		{
+			name:    "follow an artist that is already followed, but with the wrong code",
+			call:    func(c *Client) error { return c.FollowArtist(t.Context(), 27) },
+			method:  "artist.addFavorite",
+			errs:    map[string]string{"ERROR_DATA_NOT_EXISTS": "Artist does not exist"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+				if tt.errs != nil {
+					return nil, tt.errs
+				}
+				return true, nil
+			})
+
+			err := tt.call(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if got := (*calls)[0].Method; got != tt.method {
+				t.Fatalf("called %s, want %s", got, tt.method)
+			}
+		})
+	}
+}
+
+func TestFavoriteBatch(t *testing.T) {
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		if call.Payload["ALB_ID"] == "2" {
+			return nil, map[string]string{"ERROR_DATA_NOT_FOUND": "Album does not exist"}
+		}
+		return true, nil
+	})
+
+	err := c.FavoriteAlbums(t.Context(), 1, 2, 3)
+
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) || gwErr.Code != "ERROR_DATA_NOT_FOUND" {
+		t.Fatalf("got %v, want the error of album 2", err)
+	}
+	if len(*calls) != 3 {
+		t.Fatalf("made %d calls, want one per album", len(*calls))
+	}
+}
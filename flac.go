@@ -0,0 +1,231 @@
+package miri
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// flacMaxFrameSize bounds how much data is buffered while waiting for the end of a frame.
+// The largest possible frame (65535 samples of 8 channels of 32 bits, stored verbatim) is about 2 MiB.
+const flacMaxFrameSize = 4 << 20
+
+const (
+	flacMarker = iota
+	flacBlockHeader
+	flacBlockBody
+	flacFrames
+	flacDone
+)
+
+var (
+	crc8Table  = makeCRC8Table(0x07)
+	crc16Table = makeCRC16Table(0x8005)
+
+	// errShortFrame is returned while decoding a frame that has not been fully written yet.
+	errShortFrame = errors.New("short FLAC frame")
+)
+
+// flacStreamInfo holds the fields of the STREAMINFO block needed to decode the frames.
+type flacStreamInfo struct {
+	channels      int
+	bitsPerSample int
+	totalSamples  uint64 // 0 if unknown
+	md5           [md5.Size]byte
+}
+
+// flacVerifier decodes a FLAC stream as it is written, checking the CRCs of its frames
+// and the MD5 of the decoded audio against the one stored in STREAMINFO.
+type flacVerifier struct {
+	buf       []byte
+	state     int
+	blockType byte
+	skip      int64 // bytes left in the current metadata block
+	lastBlock bool
+	info      *flacStreamInfo
+	frame     flacFrame
+	audioMD5  hash.Hash
+	samples   uint64 // decoded samples per channel
+	frames    int
+	wait      int // buffer size to reach before retrying a short frame
+	err       error
+}
+
+func (f *flacVerifier) Write(p []byte) (int, error) {
+	if f.err == nil && f.state != flacDone {
+		f.buf = append(f.buf, p...)
+		f.process(false)
+	}
+
+	return len(p), nil
+}
+
+// finish decodes the last frame once the whole stream has been written and checks the audio MD5.
+func (f *flacVerifier) finish() error {
+	if f.err == nil {
+		f.process(true)
+	}
+
+	switch {
+	case f.err != nil:
+		return f.err
+	case f.state < flacFrames:
+		return fmt.Errorf("%w: truncated FLAC metadata", ErrCorruptedMedia)
+	case f.frames == 0:
+		return fmt.Errorf("%w: no FLAC audio frames", ErrCorruptedMedia)
+	case f.info.totalSamples != 0 && f.samples != f.info.totalSamples:
+		return fmt.Errorf("%w: got %d of %d FLAC samples", ErrCorruptedMedia, f.samples, f.info.totalSamples)
+	case f.info.md5 != [md5.Size]byte{} && !bytes.Equal(f.audioMD5.Sum(nil), f.info.md5[:]):
+		return fmt.Errorf("%w: decoded audio does not match the FLAC stream MD5", ErrCorruptedMedia)
+	}
+
+	return nil
+}
+
+func (f *flacVerifier) process(final bool) {
+	for {
+		switch f.state {
+		case flacMarker:
+			if len(f.buf) < 4 {
+				return
+			}
+			if !bytes.Equal(f.buf[:4], []byte("fLaC")) {
+				f.fail(fmt.Errorf("%w: missing FLAC marker", ErrCorruptedMedia))
+				return
+			}
+			f.buf = f.buf[4:]
+			f.state = flacBlockHeader
+		case flacBlockHeader:
+			if len(f.buf) < 4 {
+				return
+			}
+			f.lastBlock = f.buf[0]&0x80 != 0
+			f.blockType = f.buf[0] & 0x7f
+			f.skip = int64(binary.BigEndian.Uint32(f.buf[:4]) & 0xffffff)
+			f.buf = f.buf[4:]
+			f.state = flacBlockBody
+
+			if f.info == nil && f.blockType != 0 {
+				f.fail(fmt.Errorf("%w: missing FLAC STREAMINFO block", ErrCorruptedMedia))
+				return
+			}
+		case flacBlockBody:
+			if f.blockType == 0 && f.info == nil {
+				if len(f.buf) < 34 {
+					return
+				}
+				info, err := parseFLACStreamInfo(f.buf[:34])
+				if err != nil {
+					f.fail(err)
+					return
+				}
+				f.info = info
+				f.audioMD5 = md5.New()
+			}
+
+			n := min(f.skip, int64(len(f.buf)))
+			f.buf = f.buf[n:]
+			f.skip -= n
+			if f.skip > 0 {
+				return
+			}
+			f.state = flacBlockHeader
+			if f.lastBlock {
+				f.state = flacFrames
+			}
+		case flacFrames:
+			f.decodeFrames(final)
+			return
+		case flacDone:
+			return
+		}
+	}
+}
+
+func (f *flacVerifier) decodeFrames(final bool) {
+	if !final && len(f.buf) < f.wait {
+		return
+	}
+
+	for len(f.buf) > 0 {
+		n, err := f.frame.decode(f.buf, f.info, f.audioMD5)
+		switch {
+		case errors.Is(err, errShortFrame) && !final && len(f.buf) < flacMaxFrameSize:
+			// decoding is retried from the start of the frame, so wait for a good amount of data first
+			f.wait = 2 * len(f.buf)
+			return
+		case errors.Is(err, errShortFrame):
+			f.fail(fmt.Errorf("%w: truncated FLAC frame after %d valid frames", ErrCorruptedMedia, f.frames))
+			return
+		case err != nil:
+			f.fail(fmt.Errorf("%w: FLAC frame %d: %w", ErrCorruptedMedia, f.frames, err))
+			return
+		}
+
+		f.buf = f.buf[n:]
+		f.frames++
+		f.samples += uint64(f.frame.blockSize)
+
+		if f.info.totalSamples != 0 && f.samples >= f.info.totalSamples {
+			// anything after the last sample, like a trailing tag, is not audio
+			f.state = flacDone
+			f.buf = nil
+			return
+		}
+	}
+}
+
+func (f *flacVerifier) fail(err error) {
+	f.err = err
+	f.buf = nil
+}
+
+func parseFLACStreamInfo(b []byte) (*flacStreamInfo, error) {
+	info := &flacStreamInfo{
+		channels:      int(b[12]>>1&0x07) + 1,
+		bitsPerSample: int(b[12]&0x01)<<4 | int(b[13]>>4) + 1,
+		totalSamples:  binary.BigEndian.Uint64(b[10:18]) & 0xfffffffff,
+	}
+	copy(info.md5[:], b[18:34])
+
+	if info.bitsPerSample < 4 {
+		return nil, fmt.Errorf("%w: invalid FLAC STREAMINFO block", ErrCorruptedMedia)
+	}
+
+	return info, nil
+}
+
+func makeCRC8Table(poly byte) (table [256]byte) {
+	for i := range table {
+		crc := byte(i)
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return table
+}
+
+func makeCRC16Table(poly uint16) (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return table
+}
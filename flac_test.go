@@ -0,0 +1,428 @@
+package miri
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+)
+
+// bitWriter is the encoding counterpart of bitReader.
+type bitWriter struct {
+	buf  []byte
+	used int // bits used in the last byte
+}
+
+func (w *bitWriter) write(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.used == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>i&1) << (7 - w.used)
+		w.used = (w.used + 1) % 8
+	}
+}
+
+func (w *bitWriter) writeSigned(v int64, n int) {
+	w.write(uint64(v)&(1<<n-1), n)
+}
+
+func (w *bitWriter) writeUnary(n uint64) {
+	for range n {
+		w.write(0, 1)
+	}
+	w.write(1, 1)
+}
+
+// testEncoding selects how testFLAC encodes the frames. Each frame cycles through
+// the subframe kinds and, for stereo, through the channel assignments.
+type testEncoding struct {
+	bitsPerSample  int
+	blockSize      int
+	partitionOrder int
+	escape         bool // store the second residual partition unencoded
+	wasted         bool // strip the zero low bits shared by all samples
+	sampleSizeCode bool // store the sample size in the frame headers instead of referring to STREAMINFO
+}
+
+// testFLAC encodes samples, given per channel, to a FLAC stream whose STREAMINFO carries sum.
+func testFLAC(samples [][]int64, enc testEncoding, sum [md5.Size]byte) []byte {
+	channels, total := len(samples), len(samples[0])
+
+	w := &bitWriter{buf: []byte("fLaC")}
+	w.write(1, 1) // last metadata block
+	w.write(0, 7) // STREAMINFO
+	w.write(34, 24)
+	w.write(uint64(enc.blockSize), 16)
+	w.write(uint64(enc.blockSize), 16)
+	w.write(0, 24)
+	w.write(0, 24)
+	w.write(44100, 20)
+	w.write(uint64(channels-1), 3)
+	w.write(uint64(enc.bitsPerSample-1), 5)
+	w.write(uint64(total), 36)
+	w.buf = append(w.buf, sum[:]...)
+
+	kinds := []string{"verbatim", "fixed", "lpc"}
+	for frame, start := 0, 0; start < total; frame, start = frame+1, start+enc.blockSize {
+		block := make([][]int64, channels)
+		for ch := range block {
+			block[ch] = samples[ch][start:min(start+enc.blockSize, total)]
+		}
+
+		channelCode := channels - 1
+		if channels == 2 && frame%4 != 0 {
+			channelCode = 7 + frame%4
+			block = decorrelateTest(block, channelCode)
+		}
+
+		header := &bitWriter{}
+		header.write(0x7ffc, 15)
+		header.write(0, 1)
+		blockSizeCode := 7
+		switch size := len(block[0]); {
+		case size == 4096:
+			blockSizeCode = 12
+		case size == 192:
+			blockSizeCode = 1
+		case size == 1152:
+			blockSizeCode = 3
+		case size <= 256:
+			blockSizeCode = 6
+		}
+		header.write(uint64(blockSizeCode), 4)
+		header.write(9, 4) // 44.1 kHz
+		header.write(uint64(channelCode), 4)
+		sampleSizeCode := 0
+		if enc.sampleSizeCode {
+			sampleSizeCode = map[int]int{8: 1, 12: 2, 16: 4, 20: 5, 24: 6, 32: 7}[enc.bitsPerSample]
+		}
+		header.write(uint64(sampleSizeCode), 3)
+		header.write(0, 1)
+		header.buf = utf8.AppendRune(header.buf, rune(frame))
+		switch blockSizeCode {
+		case 6:
+			header.write(uint64(len(block[0])-1), 8)
+		case 7:
+			header.write(uint64(len(block[0])-1), 16)
+		}
+		var crc8 byte
+		for _, c := range header.buf {
+			crc8 = crc8Table[crc8^c]
+		}
+		header.write(uint64(crc8), 8)
+
+		for ch := range block {
+			bps := enc.bitsPerSample
+			if (channelCode == 8 || channelCode == 10) && ch == 1 || channelCode == 9 && ch == 0 {
+				bps++
+			}
+			encodeTestSubframe(header, block[ch], bps, kinds[(frame+ch)%len(kinds)], frame%5, enc)
+		}
+
+		header.used = 0 // pad to a byte boundary
+		var crc16 uint16
+		for _, c := range header.buf {
+			crc16 = crc16<<8 ^ crc16Table[byte(crc16>>8)^c]
+		}
+		header.write(uint64(crc16), 16)
+
+		w.buf = append(w.buf, header.buf...)
+	}
+
+	return w.buf
+}
+
+func decorrelateTest(block [][]int64, channelCode int) [][]int64 {
+	left, right := block[0], block[1]
+	a, b := make([]int64, len(left)), make([]int64, len(left))
+	for i := range left {
+		side := left[i] - right[i]
+		switch channelCode {
+		case 8:
+			a[i], b[i] = left[i], side
+		case 9:
+			a[i], b[i] = side, right[i]
+		case 10:
+			a[i], b[i] = (left[i]+right[i])>>1, side
+		}
+	}
+
+	return [][]int64{a, b}
+}
+
+func encodeTestSubframe(w *bitWriter, samples []int64, bps int, kind string, order int, enc testEncoding) {
+	constant := true
+	for _, s := range samples {
+		constant = constant && s == samples[0]
+	}
+
+	wasted := 0
+	if enc.wasted && !constant {
+		var all int64
+		for _, s := range samples {
+			all |= s
+		}
+		wasted = bits.TrailingZeros64(uint64(all))
+	}
+
+	shifted := make([]int64, len(samples))
+	for i, s := range samples {
+		shifted[i] = s >> wasted
+	}
+	bps -= wasted
+
+	writeHeader := func(kind int) {
+		w.write(0, 1)
+		w.write(uint64(kind), 6)
+		if wasted > 0 {
+			w.write(1, 1)
+			w.writeUnary(uint64(wasted - 1))
+		} else {
+			w.write(0, 1)
+		}
+	}
+
+	switch {
+	case constant:
+		writeHeader(0)
+		w.writeSigned(shifted[0], bps)
+	case kind == "verbatim" || len(samples) < 32:
+		writeHeader(1)
+		for _, s := range shifted {
+			w.writeSigned(s, bps)
+		}
+	case kind == "fixed":
+		writeHeader(8 + order)
+		coefs := [][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}[order]
+		encodeTestPrediction(w, shifted, bps, coefs, 0, -1, enc)
+	case kind == "lpc":
+		coefs := []int64{1600, -900, 250, -30, 7, -1}[:order+1]
+		writeHeader(32 + len(coefs) - 1)
+		encodeTestPrediction(w, shifted, bps, coefs, 10, 12, enc)
+	}
+}
+
+// encodeTestPrediction writes the warm-up samples, the LPC parameters if precision
+// is positive, and the residual of the prediction.
+func encodeTestPrediction(w *bitWriter, samples []int64, bps int, coefs []int64, shift, precision int, enc testEncoding) {
+	order := len(coefs)
+	for _, s := range samples[:order] {
+		w.writeSigned(s, bps)
+	}
+	if precision > 0 {
+		w.write(uint64(precision-1), 4)
+		w.writeSigned(int64(shift), 5)
+		for _, c := range coefs {
+			w.writeSigned(c, precision)
+		}
+	}
+
+	residual := make([]int64, len(samples))
+	for i := order; i < len(samples); i++ {
+		var sum int64
+		for j, c := range coefs {
+			sum += c * samples[i-1-j]
+		}
+		residual[i] = samples[i] - sum>>shift
+	}
+
+	partitions := 1 << enc.partitionOrder
+	size := len(samples) >> enc.partitionOrder
+	if size<<enc.partitionOrder != len(samples) || size < order {
+		partitions, size = 1, len(samples)
+	}
+
+	params := make([]int, partitions)
+	method := 0
+	for p := range partitions {
+		var total uint64
+		for _, r := range residual[max(p*size, order) : (p+1)*size] {
+			total += zigzag(r)
+		}
+		params[p] = bits.Len64(total / uint64(size))
+		if params[p] >= 15 {
+			method = 1
+		}
+	}
+
+	w.write(uint64(method), 2)
+	w.write(uint64(bits.Len(uint(partitions))-1), 4)
+	for p := range partitions {
+		part := residual[max(p*size, order) : (p+1)*size]
+		if enc.escape && p == min(1, partitions-1) {
+			width := 0
+			for _, r := range part {
+				width = max(width, bits.Len64(uint64(max(r, -r-1)))+1)
+			}
+			w.write(uint64(15+16*method), 4+method)
+			w.write(uint64(width), 5)
+			for _, r := range part {
+				w.writeSigned(r, width)
+			}
+			continue
+		}
+
+		param := params[p]
+		w.write(uint64(param), 4+method)
+		for _, r := range part {
+			v := zigzag(r)
+			w.writeUnary(v >> param)
+			w.write(v, param)
+		}
+	}
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1 ^ v>>63)
+}
+
+// testAudio returns a noisy sine wave of the given length, with a stretch of silence
+// so that constant subframes are used too.
+func testAudio(seed int64, channels, length, bitsPerSample, wasted int) [][]int64 {
+	rnd := rand.New(rand.NewSource(seed))
+	peak := float64(int64(1)<<(bitsPerSample-1) - 1)
+
+	samples := make([][]int64, channels)
+	for ch := range samples {
+		samples[ch] = make([]int64, length)
+		for i := range samples[ch] {
+			if i >= length/3 && i < length/2 {
+				continue
+			}
+			v := 0.6*math.Sin(float64(i*(ch+1))/20) + 0.05*rnd.NormFloat64()
+			samples[ch][i] = int64(max(-1, min(v, 1))*peak) >> wasted << wasted
+		}
+	}
+
+	return samples
+}
+
+// testAudioMD5 computes the STREAMINFO MD5 of samples.
+func testAudioMD5(samples [][]int64, bitsPerSample int) (sum [md5.Size]byte) {
+	var out []byte
+	for i := range samples[0] {
+		for ch := range samples {
+			for k := range (bitsPerSample + 7) / 8 {
+				out = append(out, byte(samples[ch][i]>>(8*k)))
+			}
+		}
+	}
+
+	return md5.Sum(out)
+}
+
+func verifyFLAC(data []byte, writeSize int) error {
+	v := newDownloadVerifier()
+	for len(data) > 0 {
+		n := min(writeSize, len(data))
+		v.Write(data[:n])
+		data = data[n:]
+	}
+
+	_, err := v.result(nil, "FLAC", -1)
+	return err
+}
+
+func TestFLACVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels int
+		length   int
+		wasted   int
+		enc      testEncoding
+	}{
+		{"stereo 16 bits", 2, 4096*12 + 1000, 0, testEncoding{bitsPerSample: 16, blockSize: 4096}},
+		{"partitions", 2, 4096 * 6, 0, testEncoding{bitsPerSample: 16, blockSize: 4096, partitionOrder: 3, sampleSizeCode: true}},
+		{"mono 24 bits", 1, 1152*9 + 7, 0, testEncoding{bitsPerSample: 24, blockSize: 1152, partitionOrder: 2}},
+		{"escaped partitions", 2, 4096 * 5, 0, testEncoding{bitsPerSample: 16, blockSize: 4096, partitionOrder: 1, escape: true}},
+		{"wasted bits", 2, 192 * 40, 4, testEncoding{bitsPerSample: 24, blockSize: 192, wasted: true}},
+		{"small blocks", 2, 200 * 300, 0, testEncoding{bitsPerSample: 8, blockSize: 200, sampleSizeCode: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := testAudio(int64(tt.length), tt.channels, tt.length, tt.enc.bitsPerSample, tt.wasted)
+			data := testFLAC(samples, tt.enc, testAudioMD5(samples, tt.enc.bitsPerSample))
+
+			for _, writeSize := range []int{1, 7, 4096, len(data)} {
+				if err := verifyFLAC(data, writeSize); err != nil {
+					t.Fatalf("write size %d: %v", writeSize, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFLACVerifierCorrupted(t *testing.T) {
+	enc := testEncoding{bitsPerSample: 16, blockSize: 4096}
+	samples := testAudio(1, 2, 4096*10, 16, 0)
+	sum := testAudioMD5(samples, 16)
+	valid := testFLAC(samples, enc, sum)
+
+	flipped := bytes.Clone(valid)
+	flipped[len(flipped)/2] ^= 0x01
+
+	// the frames of other audio are valid on their own, but don't match the MD5
+	other := testAudio(2, 2, 4096*10, 16, 0)
+	mismatched := testFLAC(other, enc, sum)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"flipped bit", flipped},
+		{"mismatched MD5", mismatched},
+		{"truncated frame", valid[:len(valid)-100]},
+		{"truncated metadata", valid[:20]},
+		{"no frames", valid[:flacHeaderSize]},
+		{"garbage after metadata", append(bytes.Clone(valid[:flacHeaderSize]), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyFLAC(tt.data, 4096); !errors.Is(err, ErrCorruptedMedia) {
+				t.Fatalf("got %v, want %v", err, ErrCorruptedMedia)
+			}
+		})
+	}
+}
+
+func TestFLACVerifierWithoutMD5(t *testing.T) {
+	enc := testEncoding{bitsPerSample: 16, blockSize: 4096}
+	samples := testAudio(1, 2, 4096*3, 16, 0)
+	data := testFLAC(samples, enc, [md5.Size]byte{})
+
+	if err := verifyFLAC(data, 4096); err != nil {
+		t.Fatal(err)
+	}
+
+	data[len(data)-3] ^= 0x01
+	if err := verifyFLAC(data, 4096); !errors.Is(err, ErrCorruptedMedia) {
+		t.Fatalf("got %v, want %v", err, ErrCorruptedMedia)
+	}
+}
+
+func TestFLACVerifierIgnoresTrailingData(t *testing.T) {
+	enc := testEncoding{bitsPerSample: 16, blockSize: 4096}
+	samples := testAudio(1, 2, 4096*3, 16, 0)
+	data := testFLAC(samples, enc, testAudioMD5(samples, 16))
+	data = append(data, []byte("TAG some trailing ID3v1 tag")...)
+
+	if err := verifyFLAC(data, 4096); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFLACVerifierIgnoresOtherFormats(t *testing.T) {
+	v := newDownloadVerifier()
+	v.Write([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"))
+
+	if _, err := v.result(nil, "MP3_320", -1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,453 @@
+package miri
+
+import (
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+var errInvalidFrame = errors.New("invalid frame")
+
+// flacFrame decodes FLAC frames, reusing its sample buffers from one frame to the next.
+type flacFrame struct {
+	blockSize int
+	channels  [][]int64
+	out       []byte
+}
+
+// decode decodes the frame at the start of b, checks its CRCs and adds its samples to sum.
+// It returns the size of the frame, or errShortFrame if b does not hold all of it.
+func (f *flacFrame) decode(b []byte, info *flacStreamInfo, sum hash.Hash) (int, error) {
+	r := &bitReader{data: b}
+
+	sync, err := r.read(15)
+	if err != nil {
+		return 0, err
+	}
+	if sync != 0x7ffc {
+		return 0, errors.New("missing frame sync code")
+	}
+
+	// blocking strategy, block size, sample rate, channels, sample size and reserved bit
+	r.read(1)
+	blockSizeCode, _ := r.read(4)
+	sampleRateCode, _ := r.read(4)
+	channelCode, _ := r.read(4)
+	sampleSizeCode, _ := r.read(3)
+	reserved, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	if blockSizeCode == 0 || sampleRateCode == 15 || channelCode > 10 || sampleSizeCode == 3 || reserved != 0 {
+		return 0, errInvalidFrame
+	}
+
+	if err := r.skipCodedNumber(); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case blockSizeCode == 1:
+		f.blockSize = 192
+	case blockSizeCode <= 5:
+		f.blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		n, err := r.read(8)
+		if err != nil {
+			return 0, err
+		}
+		f.blockSize = int(n) + 1
+	case blockSizeCode == 7:
+		n, err := r.read(16)
+		if err != nil {
+			return 0, err
+		}
+		f.blockSize = int(n) + 1
+	default:
+		f.blockSize = 256 << (blockSizeCode - 8)
+	}
+
+	switch sampleRateCode {
+	case 12:
+		_, err = r.read(8)
+	case 13, 14:
+		_, err = r.read(16)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	headerEnd := r.pos/8 + 1
+	if headerEnd > len(b) {
+		return 0, errShortFrame
+	}
+	var crc8 byte
+	for _, c := range b[:headerEnd] {
+		crc8 = crc8Table[crc8^c]
+	}
+	if crc8 != 0 {
+		return 0, errors.New("frame header CRC mismatch")
+	}
+	r.pos += 8
+
+	bitsPerSample := info.bitsPerSample
+	if sampleSizeCode != 0 {
+		bitsPerSample = []int{0, 8, 12, 0, 16, 20, 24, 32}[sampleSizeCode]
+	}
+	channels := int(channelCode) + 1
+	if channelCode > 7 {
+		channels = 2
+	}
+	if channels != info.channels || bitsPerSample != info.bitsPerSample {
+		return 0, errors.New("frame format does not match STREAMINFO")
+	}
+
+	for len(f.channels) < channels {
+		f.channels = append(f.channels, nil)
+	}
+	for ch := range channels {
+		if cap(f.channels[ch]) < f.blockSize {
+			f.channels[ch] = make([]int64, f.blockSize)
+		}
+		f.channels[ch] = f.channels[ch][:f.blockSize]
+
+		// the side channel needs an extra bit
+		bps := bitsPerSample
+		if (channelCode == 8 || channelCode == 10) && ch == 1 || channelCode == 9 && ch == 0 {
+			bps++
+		}
+		if err := r.decodeSubframe(f.channels[ch], bps); err != nil {
+			return 0, err
+		}
+	}
+
+	// the subframes are padded to a byte boundary and followed by the CRC-16 of the frame
+	end := (r.pos+7)/8 + 2
+	if end > len(b) {
+		return 0, errShortFrame
+	}
+	var crc16 uint16
+	for _, c := range b[:end] {
+		crc16 = crc16<<8 ^ crc16Table[byte(crc16>>8)^c]
+	}
+	if crc16 != 0 {
+		return 0, errors.New("frame CRC mismatch")
+	}
+
+	f.decorrelate(channelCode)
+	f.hash(sum, channels, bitsPerSample)
+
+	return end, nil
+}
+
+// decorrelate restores the left and right channels of stereo frames.
+func (f *flacFrame) decorrelate(channelCode uint64) {
+	if channelCode < 8 {
+		return
+	}
+
+	a, b := f.channels[0], f.channels[1]
+	for i := range f.blockSize {
+		switch channelCode {
+		case 8: // left, side
+			b[i] = a[i] - b[i]
+		case 9: // side, right
+			a[i] += b[i]
+		case 10: // mid, side
+			mid := a[i]<<1 | b[i]&1
+			a[i], b[i] = (mid+b[i])>>1, (mid-b[i])>>1
+		}
+	}
+}
+
+// hash writes the interleaved samples to sum as little-endian signed integers,
+// which is how the STREAMINFO MD5 is computed.
+func (f *flacFrame) hash(sum hash.Hash, channels, bitsPerSample int) {
+	width := (bitsPerSample + 7) / 8
+	f.out = f.out[:0]
+	for i := range f.blockSize {
+		for ch := range channels {
+			s := f.channels[ch][i]
+			for k := range width {
+				f.out = append(f.out, byte(s>>(8*k)))
+			}
+		}
+	}
+
+	sum.Write(f.out)
+}
+
+// decodeSubframe decodes a subframe of len(out) samples of bps bits.
+func (r *bitReader) decodeSubframe(out []int64, bps int) error {
+	padding, err := r.read(1)
+	if err != nil {
+		return err
+	}
+	kind, _ := r.read(6)
+	hasWasted, err := r.read(1)
+	if err != nil {
+		return err
+	}
+	if padding != 0 {
+		return errInvalidFrame
+	}
+
+	wasted := 0
+	if hasWasted == 1 {
+		n, err := r.unary()
+		if err != nil {
+			return err
+		}
+		wasted = int(n) + 1
+		if wasted >= bps {
+			return errInvalidFrame
+		}
+		bps -= wasted
+	}
+
+	switch {
+	case kind == 0: // constant
+		v, err := r.readSigned(bps)
+		if err != nil {
+			return err
+		}
+		for i := range out {
+			out[i] = v
+		}
+	case kind == 1: // verbatim
+		for i := range out {
+			if out[i], err = r.readSigned(bps); err != nil {
+				return err
+			}
+		}
+	case kind >= 8 && kind <= 12:
+		if err := r.decodeFixed(out, int(kind-8), bps); err != nil {
+			return err
+		}
+	case kind >= 32:
+		if err := r.decodeLPC(out, int(kind-31), bps); err != nil {
+			return err
+		}
+	default:
+		return errInvalidFrame
+	}
+
+	if wasted > 0 {
+		for i := range out {
+			out[i] <<= wasted
+		}
+	}
+
+	return nil
+}
+
+func (r *bitReader) decodeFixed(out []int64, order, bps int) error {
+	if order > len(out) {
+		return errInvalidFrame
+	}
+	for i := range order {
+		var err error
+		if out[i], err = r.readSigned(bps); err != nil {
+			return err
+		}
+	}
+	if err := r.decodeResidual(out, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(out); i++ {
+		switch order {
+		case 1:
+			out[i] += out[i-1]
+		case 2:
+			out[i] += 2*out[i-1] - out[i-2]
+		case 3:
+			out[i] += 3*out[i-1] - 3*out[i-2] + out[i-3]
+		case 4:
+			out[i] += 4*out[i-1] - 6*out[i-2] + 4*out[i-3] - out[i-4]
+		}
+	}
+
+	return nil
+}
+
+func (r *bitReader) decodeLPC(out []int64, order, bps int) error {
+	if order > len(out) {
+		return errInvalidFrame
+	}
+	for i := range order {
+		var err error
+		if out[i], err = r.readSigned(bps); err != nil {
+			return err
+		}
+	}
+
+	precision, _ := r.read(4)
+	shift, err := r.readSigned(5)
+	if err != nil {
+		return err
+	}
+	if precision == 15 || shift < 0 {
+		return errInvalidFrame
+	}
+
+	coefs := make([]int64, order)
+	for i := range coefs {
+		if coefs[i], err = r.readSigned(int(precision) + 1); err != nil {
+			return err
+		}
+	}
+	if err := r.decodeResidual(out, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(out); i++ {
+		var sum int64
+		for j, c := range coefs {
+			sum += c * out[i-1-j]
+		}
+		out[i] += sum >> shift
+	}
+
+	return nil
+}
+
+// decodeResidual decodes the rice-coded residual of out[order:].
+func (r *bitReader) decodeResidual(out []int64, order int) error {
+	method, _ := r.read(2)
+	partitionOrder, err := r.read(4)
+	if err != nil {
+		return err
+	}
+	if method > 1 {
+		return errInvalidFrame
+	}
+
+	paramBits, escape := 4, uint64(15)
+	if method == 1 {
+		paramBits, escape = 5, 31
+	}
+
+	partitions := 1 << partitionOrder
+	size := len(out) >> partitionOrder
+	if size<<partitionOrder != len(out) || size < order {
+		return errInvalidFrame
+	}
+
+	i := order
+	for p := range partitions {
+		end := (p + 1) * size
+
+		param, err := r.read(paramBits)
+		if err != nil {
+			return err
+		}
+
+		if param == escape {
+			width, err := r.read(5)
+			if err != nil {
+				return err
+			}
+			for ; i < end; i++ {
+				if out[i], err = r.readSigned(int(width)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		for ; i < end; i++ {
+			high, err := r.unary()
+			if err != nil {
+				return err
+			}
+			low, err := r.read(int(param))
+			if err != nil {
+				return err
+			}
+			v := high<<param | low
+			out[i] = int64(v>>1) ^ -int64(v&1)
+		}
+	}
+
+	return nil
+}
+
+// bitReader reads big-endian bit fields from a buffer, failing with errShortFrame past its end.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) read(n int) (uint64, error) {
+	if r.pos+n > len(r.data)*8 {
+		return 0, errShortFrame
+	}
+
+	var v uint64
+	for n > 0 {
+		offset := r.pos & 7
+		take := min(8-offset, n)
+		chunk := r.data[r.pos>>3] >> (8 - offset - take) & (1<<take - 1)
+		v = v<<take | uint64(chunk)
+		n -= take
+		r.pos += take
+	}
+
+	return v, nil
+}
+
+func (r *bitReader) readSigned(n int) (int64, error) {
+	v, err := r.read(n)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// unary counts the zero bits before the next one bit, which is consumed.
+func (r *bitReader) unary() (uint64, error) {
+	var n uint64
+	for {
+		if r.pos>>3 >= len(r.data) {
+			return 0, errShortFrame
+		}
+
+		offset := r.pos & 7
+		if rest := r.data[r.pos>>3] << offset; rest != 0 {
+			zeros := bits.LeadingZeros8(rest)
+			r.pos += zeros + 1
+			return n + uint64(zeros), nil
+		}
+
+		n += uint64(8 - offset)
+		r.pos += 8 - offset
+	}
+}
+
+// skipCodedNumber skips the frame or sample number, coded like UTF-8 on up to 7 bytes.
+func (r *bitReader) skipCodedNumber() error {
+	first, err := r.read(8)
+	if err != nil {
+		return err
+	}
+	if first&0x80 == 0 {
+		return nil
+	}
+	if first == 0xff || first&0xc0 == 0x80 {
+		return errInvalidFrame
+	}
+
+	for mask := uint64(0x40); first&mask != 0; mask >>= 1 {
+		c, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		if c&0xc0 != 0x80 {
+			return errInvalidFrame
+		}
+	}
+
+	return nil
+}
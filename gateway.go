@@ -0,0 +1,118 @@
+package miri
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const defaultGatewayURL = "https://www.deezer.com/ajax/gw-light.php"
+
+// GatewayError is an error returned by a method of the private API.
+type GatewayError struct {
+	Method  string
+	Code    string // e.g. "DATA_ERROR" or "ERROR_DATA_NOT_FOUND"
+	Message string
+}
+
+func (e *GatewayError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Method, e.Code, e.Message)
+}
+
+type gatewayResponse struct {
+	Error   json.RawMessage `json:"error"`
+	Results json.RawMessage `json:"results"`
+}
+
+// openGateway calls a method of the private API and returns the response body.
+func (c *Client) openGateway(ctx context.Context, session *Session, method string, payload any) (io.ReadCloser, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayURL := c.appConfig.GatewayURL
+	if gatewayURL == "" {
+		gatewayURL = defaultGatewayURL
+	}
+
+	url := fmt.Sprintf("%s?method=%s&input=3&api_version=1.0&api_token=%s", gatewayURL, method, session.APIToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := session.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, ErrQuotaExceeded
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// callGateway calls a method of the private API and decodes its results into result, if not nil.
+func (c *Client) callGateway(ctx context.Context, method string, payload, result any) error {
+	return c.withSession(ctx, func(session *Session) error {
+		body, err := c.openGateway(ctx, session, method, payload)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		var res gatewayResponse
+		if err := json.NewDecoder(body).Decode(&res); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+
+		if err := gatewayError(res.Error); err != nil {
+			var gwErr *GatewayError
+			if errors.As(err, &gwErr) {
+				gwErr.Method = method
+			}
+			return err
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		return json.Unmarshal(res.Results, result)
+	})
+}
+
+// gatewayError converts the error field of a gateway response, which is an empty array on success.
+func gatewayError(raw json.RawMessage) error {
+	var errs map[string]any
+	if err := json.Unmarshal(raw, &errs); err != nil || len(errs) == 0 {
+		return nil
+	}
+
+	if _, ok := errs["VALID_TOKEN_REQUIRED"]; ok {
+		return ErrSessionExpired
+	}
+
+	keys := make([]string, 0, len(errs))
+	for k := range errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return &GatewayError{Code: keys[0], Message: fmt.Sprint(errs[keys[0]])}
+}
@@ -0,0 +1,91 @@
+package miri
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gatewayCall is a request received by a fake gateway.
+type gatewayCall struct {
+	Method  string
+	Payload map[string]any
+}
+
+// newGatewayClient returns a client whose private API calls are answered by respond,
+// which returns the results and error fields of the response for each method.
+func newGatewayClient(t *testing.T, respond func(call gatewayCall) (results, errs any)) (*Client, *[]gatewayCall) {
+	t.Helper()
+
+	var calls []gatewayCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := gatewayCall{Method: r.URL.Query().Get("method")}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &call.Payload); err != nil {
+			t.Errorf("invalid payload for %s: %v", call.Method, err)
+		}
+		calls = append(calls, call)
+
+		results, errs := respond(call)
+		if errs == nil {
+			errs = []any{}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"error": errs, "results": results})
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &Client{
+		appConfig: &Config{GatewayURL: srv.URL},
+		session:   &Session{APIToken: "token", HttpClient: srv.Client()},
+	}
+
+	return c, &calls
+}
+
+func TestGatewayError(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want error
+	}{
+		{`[]`, nil},
+		{`{}`, nil},
+		{`{"VALID_TOKEN_REQUIRED":"Invalid CSRF token"}`, ErrSessionExpired},
+		{`{"DATA_ERROR":"playlist::getData"}`, &GatewayError{Code: "DATA_ERROR", Message: "playlist::getData"}},
+		{`{"REQUEST_ERROR":"b","DATA_ERROR":"a"}`, &GatewayError{Code: "DATA_ERROR", Message: "a"}},
+	}
+
+	for _, tt := range tests {
+		err := gatewayError(json.RawMessage(tt.raw))
+
+		var want *GatewayError
+		if errors.As(tt.want, &want) {
+			var got *GatewayError
+			if !errors.As(err, &got) || *got != *want {
+				t.Errorf("%s: got %v, want %v", tt.raw, err, tt.want)
+			}
+			continue
+		}
+		if err != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.raw, err, tt.want)
+		}
+	}
+}
+
+func TestCallGatewayError(t *testing.T) {
+	c, _ := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return nil, map[string]string{"ERROR_DATA_NOT_FOUND": "Playlist not found"}
+	})
+
+	err := c.DeletePlaylist(t.Context(), 42)
+
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) {
+		t.Fatalf("got %v, want a *GatewayError", err)
+	}
+	if gwErr.Method != "playlist.delete" || gwErr.Code != "ERROR_DATA_NOT_FOUND" || gwErr.Message != "Playlist not found" {
+		t.Fatalf("unexpected gateway error: %+v", gwErr)
+	}
+}
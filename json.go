@@ -0,0 +1,102 @@
+package miri
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The gateway is inconsistent with types: numbers and booleans are sent
+// either as JSON literals or as strings, and missing dates as "0000-00-00".
+// These types accept every form and are converted to plain Go types after decoding.
+// Fields kept as strings for compatibility are converted with the same parseNumber and parseDate.
+
+// flexString keeps the textual form of a value sent either as a string or as a number.
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*f = ""
+		return nil
+	}
+
+	*f = flexString(unquote(data))
+	return nil
+}
+
+type flexInt int
+
+func (f *flexInt) UnmarshalJSON(data []byte) error {
+	n, err := parseNumber(unquote(data))
+	if err != nil {
+		return err
+	}
+
+	*f = flexInt(n)
+	return nil
+}
+
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	n, err := parseNumber(unquote(data))
+	if err != nil {
+		return err
+	}
+
+	*f = flexFloat(n)
+	return nil
+}
+
+// flexBool is true for true and non-zero numbers. Anything else is false,
+// so that an unexpected value does not fail the decoding of a whole resource.
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(data []byte) error {
+	s := unquote(data)
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		*f = n != 0
+		return nil
+	}
+
+	*f = flexBool(s == "true")
+	return nil
+}
+
+// flexDate is the zero time for missing dates and for formats it does not recognise.
+type flexDate time.Time
+
+func (f *flexDate) UnmarshalJSON(data []byte) error {
+	*f = flexDate(parseDate(unquote(data)))
+	return nil
+}
+
+// parseNumber parses a number in its textual form, which is zero when missing.
+func parseNumber(s string) (float64, error) {
+	if s == "" || s == "null" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %w", s, err)
+	}
+
+	return n, nil
+}
+
+// parseDate parses the date formats sent by the gateway, returning the zero time for any other.
+func parseDate(s string) time.Time {
+	for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func unquote(data []byte) string {
+	return string(bytes.Trim(data, `"`))
+}
@@ -0,0 +1,91 @@
+package miri
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFlexBool(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`"true"`, true},
+		{`1`, true},
+		{`"1"`, true},
+		{`2`, true},
+		{`"0"`, false},
+		{`0`, false},
+		{`""`, false},
+		{`null`, false},
+		{`"yes"`, false},
+	}
+
+	for _, tt := range tests {
+		var got flexBool
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if bool(got) != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFlexDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // empty for the zero time
+	}{
+		{`"2001-03-07"`, "2001-03-07"},
+		{`"2005-12-06 00:00:00"`, "2005-12-06"},
+		{`"2020-01-02T15:04:05Z"`, "2020-01-02"},
+		{`"0000-00-00"`, ""},
+		{`""`, ""},
+		{`null`, ""},
+		{`"07/03/2001"`, ""},
+		{`1700000000`, ""},
+	}
+
+	for _, tt := range tests {
+		var got flexDate
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+
+		date := time.Time(got)
+		switch {
+		case tt.want == "" && !date.IsZero():
+			t.Errorf("%s: got %v, want the zero time", tt.in, date)
+		case tt.want != "" && date.Format(time.DateOnly) != tt.want:
+			t.Errorf("%s: got %v, want %s", tt.in, date, tt.want)
+		}
+	}
+}
+
+func TestFlexNumbers(t *testing.T) {
+	var v struct {
+		A flexInt    `json:"a"`
+		B flexInt    `json:"b"`
+		C flexFloat  `json:"c"`
+		D flexString `json:"d"`
+		E flexString `json:"e"`
+	}
+
+	data := `{"a": 12, "b": "34", "c": "-1.5", "d": 224, "e": null}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 12 || v.B != 34 || v.C != -1.5 || v.D != "224" || v.E != "" {
+		t.Fatalf("unexpected decoding: %+v", v)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": "many"}`), &v); err == nil {
+		t.Fatal("expected an error for a non-numeric integer")
+	}
+}
@@ -0,0 +1,143 @@
+package miri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Profile tabs of deezer.pageProfile.
+const (
+	tabLoved     = "loved"
+	tabAlbums    = "albums"
+	tabPlaylists = "playlists"
+	tabArtists   = "artists"
+)
+
+// Favorites returns the loved tracks of the current user.
+func (c *Client) Favorites(ctx context.Context) ([]*Song, error) {
+	return c.UserFavorites(ctx, c.User().ID)
+}
+
+// SavedAlbums returns the albums saved by the current user.
+func (c *Client) SavedAlbums(ctx context.Context) ([]*Album, error) {
+	return c.UserAlbums(ctx, c.User().ID)
+}
+
+// FollowedArtists returns the artists followed by the current user.
+func (c *Client) FollowedArtists(ctx context.Context) ([]*Artist, error) {
+	return c.UserArtists(ctx, c.User().ID)
+}
+
+// Playlists returns the playlists created or followed by the current user.
+func (c *Client) Playlists(ctx context.Context) ([]*Playlist, error) {
+	return c.UserPlaylists(ctx, c.User().ID)
+}
+
+// UserFavorites returns the loved tracks of a user.
+func (c *Client) UserFavorites(ctx context.Context, userID int) ([]*Song, error) {
+	var songs []*Song
+	err := c.fetchProfileTab(ctx, userID, tabLoved, func(raw json.RawMessage) error {
+		song := &Song{}
+		if err := json.Unmarshal(raw, song); err != nil {
+			return err
+		}
+		songs = append(songs, song)
+		return nil
+	})
+
+	return songs, err
+}
+
+// UserAlbums returns the albums saved by a user. Their songs are not loaded.
+func (c *Client) UserAlbums(ctx context.Context, userID int) ([]*Album, error) {
+	var albums []*Album
+	err := c.fetchProfileTab(ctx, userID, tabAlbums, func(raw json.RawMessage) error {
+		album := &Album{}
+		if err := json.Unmarshal(raw, &album.Results.Data); err != nil {
+			return err
+		}
+		albums = append(albums, album)
+		return nil
+	})
+
+	return albums, err
+}
+
+// UserArtists returns the artists followed by a user. Their top songs are not loaded.
+func (c *Client) UserArtists(ctx context.Context, userID int) ([]*Artist, error) {
+	var artists []*Artist
+	err := c.fetchProfileTab(ctx, userID, tabArtists, func(raw json.RawMessage) error {
+		artist := &Artist{}
+		if err := json.Unmarshal(raw, &artist.Results.Data); err != nil {
+			return err
+		}
+		artists = append(artists, artist)
+		return nil
+	})
+
+	return artists, err
+}
+
+// UserPlaylists returns the playlists created or followed by a user. Their songs are not loaded.
+func (c *Client) UserPlaylists(ctx context.Context, userID int) ([]*Playlist, error) {
+	var playlists []*Playlist
+	err := c.fetchProfileTab(ctx, userID, tabPlaylists, func(raw json.RawMessage) error {
+		playlist := &Playlist{}
+		if err := json.Unmarshal(raw, &playlist.Results.Data); err != nil {
+			return err
+		}
+		playlists = append(playlists, playlist)
+		return nil
+	})
+
+	return playlists, err
+}
+
+// fetchProfileTab pages through a tab of a user profile, calling fn for every item.
+func (c *Client) fetchProfileTab(ctx context.Context, userID int, tab string, fn func(raw json.RawMessage) error) error {
+	if userID == 0 {
+		return fmt.Errorf("invalid user ID")
+	}
+
+	seen := make(map[string]bool)
+	for start := 0; ; {
+		payload := map[string]any{
+			"user_id": userID,
+			"tab":     tab,
+			"start":   start,
+			"nb":      pageSize,
+		}
+
+		var page struct {
+			Tab map[string]struct {
+				Data  []json.RawMessage `json:"data"`
+				Total int               `json:"total"`
+			} `json:"TAB"`
+		}
+		if err := c.callGateway(ctx, "deezer.pageProfile", payload, &page); err != nil {
+			return fmt.Errorf("failed to fetch %s of user %d: %w", tab, userID, err)
+		}
+
+		items := page.Tab[tab]
+		added := 0
+		for _, raw := range items.Data {
+			// guards against the gateway ignoring start and sending the same page again
+			key := string(raw)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			added++
+
+			if err := fn(raw); err != nil {
+				return fmt.Errorf("failed to decode %s of user %d: %w", tab, userID, err)
+			}
+		}
+
+		start += len(items.Data)
+		if added == 0 || start >= items.Total {
+			return nil
+		}
+	}
+}
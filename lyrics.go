@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
-const lyricsURLFormat = "https://lyrics.lewdhutao.my.eu.org/v2/musixmatch/lyrics?title=%s&artist=%s"
+const lyricsURL = "https://lyrics.lewdhutao.my.eu.org/v2/musixmatch/lyrics"
 
 type LyricsResponse struct {
 	Data struct {
@@ -24,12 +25,28 @@ type LyricsResponse struct {
 	} `json:"metadata"`
 }
 
-func (s *SongResult) Lyrics(ctx context.Context) (string, error) {
-	artist := url.QueryEscape(s.Artist.Name)
-	title := url.QueryEscape(s.Title)
-	lyricsURL := fmt.Sprintf(lyricsURLFormat, title, artist)
+// lyricsQuery holds the parameters used to look up lyrics.
+// ISRC and Duration are optional and help disambiguate between
+// different recordings sharing the same title.
+type lyricsQuery struct {
+	Artist   string
+	Title    string
+	ISRC     string
+	Duration int // in seconds
+}
+
+func fetchLyrics(ctx context.Context, q lyricsQuery) (string, error) {
+	p := url.Values{}
+	p.Set("title", q.Title)
+	p.Set("artist", q.Artist)
+	if q.ISRC != "" {
+		p.Set("isrc", q.ISRC)
+	}
+	if q.Duration > 0 {
+		p.Set("duration", strconv.Itoa(q.Duration))
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", lyricsURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", lyricsURL+"?"+p.Encode(), nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -50,8 +67,27 @@ func (s *SongResult) Lyrics(ctx context.Context) (string, error) {
 	}
 
 	if lyricsResp.Data.Lyrics == "" {
-		return "", fmt.Errorf("no lyrics found for %s - %s", s.Artist.Name, s.Title)
+		return "", fmt.Errorf("no lyrics found for %s - %s", q.Artist, q.Title)
 	}
 
 	return lyricsResp.Data.Lyrics, nil
 }
+
+// Lyrics fetches the lyrics of a search result by artist and title only.
+func (s *SongResult) Lyrics(ctx context.Context) (string, error) {
+	return fetchLyrics(ctx, lyricsQuery{
+		Artist: s.Artist.Name,
+		Title:  s.Title,
+	})
+}
+
+// Lyrics fetches the lyrics of a song, using its ISRC and duration
+// to improve the accuracy of the match.
+func (s *Song) Lyrics(ctx context.Context) (string, error) {
+	return fetchLyrics(ctx, lyricsQuery{
+		Artist:   s.Artist,
+		Title:    s.GetTitle(),
+		ISRC:     s.ISRC,
+		Duration: int(s.DurationTime().Seconds()),
+	})
+}
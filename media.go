@@ -1,5 +1,7 @@
 package miri
 
+import "strings"
+
 type Media struct {
 	Errors []MediaError `json:"errors"`
 	Data   []struct {
@@ -34,3 +36,46 @@ func (m *Media) GetURL() string {
 func (m *Media) GetFormat() string {
 	return m.Data[0].Media[0].Format
 }
+
+// GetContentType returns the MIME type of the media format.
+func (m *Media) GetContentType() string {
+	return contentType(m.GetFormat())
+}
+
+// GetExtension returns the file extension of the media format, including the dot.
+func (m *Media) GetExtension() string {
+	return extension(m.GetFormat())
+}
+
+func contentType(format string) string {
+	switch strings.ToUpper(format) {
+	case "FLAC":
+		return "audio/flac"
+	case "MP3_128", "MP3_320":
+		return "audio/mpeg"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// FormatExtension returns the file extension of a media format such as "MP3_320" or "FLAC", including the dot.
+func FormatExtension(format string) string {
+	return extension(format)
+}
+
+var filenameReplacer = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_",
+	"?", "_", "\"", "_", "<", "_", ">", "_", "|", "_",
+)
+
+// SanitizeFilename replaces the characters that are not allowed in file names on common filesystems.
+func SanitizeFilename(name string) string {
+	return strings.TrimSpace(filenameReplacer.Replace(name))
+}
+
+func extension(format string) string {
+	if strings.ToUpper(format) == "FLAC" {
+		return ".flac"
+	}
+	return ".mp3"
+}
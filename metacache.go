@@ -0,0 +1,156 @@
+package miri
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var defaultMetadataTTLs = map[string]time.Duration{
+	"Album":    7 * 24 * time.Hour,
+	"Track":    7 * 24 * time.Hour,
+	"Artist":   24 * time.Hour,
+	"Playlist": 10 * time.Minute,
+	"Episode":  7 * 24 * time.Hour,
+	"Show":     time.Hour,
+}
+
+// MetadataCache stores resources marshalled to JSON, keyed by resource type (as returned by GetType) and ID.
+// Entries holding songs never outlive their track tokens.
+type MetadataCache interface {
+	Get(resourceType string, id int) ([]byte, bool)
+	Set(resourceType string, id int, data []byte, ttl time.Duration)
+	Delete(resourceType string, id int)
+}
+
+// memorySweepMin is the number of entries a MemoryMetadataCache holds before it starts sweeping.
+const memorySweepMin = 64
+
+// MemoryMetadataCache is an in-memory MetadataCache. Expired entries are swept
+// whenever the number of entries doubles since the last sweep.
+type MemoryMetadataCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryMetadataEntry
+	sweepAt int
+}
+
+type memoryMetadataEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewMemoryMetadataCache() *MemoryMetadataCache {
+	return &MemoryMetadataCache{entries: make(map[string]memoryMetadataEntry), sweepAt: memorySweepMin}
+}
+
+func (m *MemoryMetadataCache) Get(resourceType string, id int) ([]byte, bool) {
+	key := metadataKey(resourceType, id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+
+	return entry.data, true
+}
+
+func (m *MemoryMetadataCache) Set(resourceType string, id int, data []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.entries[metadataKey(resourceType, id)] = memoryMetadataEntry{data: data, expires: now.Add(ttl)}
+
+	if len(m.entries) >= m.sweepAt {
+		for key, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, key)
+			}
+		}
+		m.sweepAt = max(2*len(m.entries), memorySweepMin)
+	}
+}
+
+func (m *MemoryMetadataCache) Delete(resourceType string, id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, metadataKey(resourceType, id))
+}
+
+// FileMetadataCache is a MetadataCache storing one file per resource.
+// The expiry of each entry is kept as the modification time of its file.
+type FileMetadataCache struct {
+	dir string
+}
+
+func NewFileMetadataCache(dir string) (*FileMetadataCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create metadata cache directory: %w", err)
+	}
+
+	return &FileMetadataCache{dir: dir}, nil
+}
+
+func (f *FileMetadataCache) Get(resourceType string, id int) ([]byte, bool) {
+	path := f.path(resourceType, id)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Now().After(info.ModTime()) {
+		os.Remove(path)
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (f *FileMetadataCache) Set(resourceType string, id int, data []byte, ttl time.Duration) {
+	path := f.path(resourceType, id)
+
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+
+	expires := time.Now().Add(ttl)
+	if err := os.Chtimes(tmp.Name(), expires, expires); err != nil {
+		return
+	}
+
+	os.Rename(tmp.Name(), path)
+}
+
+func (f *FileMetadataCache) Delete(resourceType string, id int) {
+	os.Remove(f.path(resourceType, id))
+}
+
+func (f *FileMetadataCache) path(resourceType string, id int) string {
+	return filepath.Join(f.dir, metadataKey(resourceType, id)+".json")
+}
+
+func metadataKey(resourceType string, id int) string {
+	return fmt.Sprintf("%s_%d", resourceType, id)
+}
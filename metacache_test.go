@@ -0,0 +1,117 @@
+package miri
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryMetadataCacheSweep(t *testing.T) {
+	m := NewMemoryMetadataCache()
+
+	for id := range 1000 {
+		m.Set("Track", id, []byte("{}"), -time.Second)
+	}
+	m.Set("Track", 1000, []byte("{}"), time.Hour)
+
+	if len(m.entries) >= memorySweepMin {
+		t.Fatalf("%d entries left, expired entries were not swept", len(m.entries))
+	}
+	if _, ok := m.Get("Track", 1000); !ok {
+		t.Fatal("the live entry was swept")
+	}
+}
+
+func TestMetadataCacheDelete(t *testing.T) {
+	file, err := NewFileMetadataCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, cache := range map[string]MetadataCache{"memory": NewMemoryMetadataCache(), "file": file} {
+		cache.Set("Playlist", 1, []byte("{}"), time.Hour)
+		cache.Set("Playlist", 2, []byte("{}"), time.Hour)
+		cache.Delete("Playlist", 1)
+		cache.Delete("Playlist", 3)
+
+		if _, ok := cache.Get("Playlist", 1); ok {
+			t.Errorf("%s: deleted entry is still cached", name)
+		}
+		if _, ok := cache.Get("Playlist", 2); !ok {
+			t.Errorf("%s: other entry was deleted", name)
+		}
+	}
+}
+
+func TestTrackTokenTTL(t *testing.T) {
+	now := time.Now()
+	expiring := func(d time.Duration) *Song {
+		return &Song{TrackToken: "token", TrackTokenExpire: now.Add(d).Unix()}
+	}
+
+	tests := []struct {
+		name  string
+		songs []*Song
+		want  time.Duration
+	}{
+		{"no songs", nil, time.Hour},
+		{"no track token", []*Song{{}}, time.Hour},
+		{"later expiry", []*Song{expiring(3 * time.Hour)}, time.Hour},
+		{"earlier expiry", []*Song{expiring(3 * time.Hour), expiring(30 * time.Minute)}, 25 * time.Minute},
+		{"unknown expiry", []*Song{expiring(3 * time.Hour), {TrackToken: "token"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trackTokenTTL(time.Hour, tt.songs)
+			if got < tt.want-time.Minute || got > tt.want {
+				t.Fatalf("got %v, want about %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := trackTokenTTL(time.Hour, []*Song{expiring(time.Minute)}); got > 0 {
+		t.Fatalf("got %v for a token expiring within the margin", got)
+	}
+}
+
+func TestFetchResourceCache(t *testing.T) {
+	var expire int64
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return map[string]any{"DATA": map[string]any{
+			"SNG_ID":             "42",
+			"SNG_TITLE":          "Title",
+			"TRACK_TOKEN":        "token",
+			"TRACK_TOKEN_EXPIRE": expire,
+		}}, nil
+	})
+	c.appConfig.MetadataCache = NewMemoryMetadataCache()
+
+	fetch := func() {
+		t.Helper()
+		track := &Track{}
+		if err := c.FetchResource(context.Background(), track, 42); err != nil {
+			t.Fatal(err)
+		}
+		if track.Results.Data == nil || track.Results.Data.Title != "Title" {
+			t.Fatalf("unexpected track: %+v", track.Results.Data)
+		}
+	}
+
+	expire = time.Now().Add(2 * time.Hour).Unix()
+	fetch()
+	fetch()
+	if len(*calls) != 1 {
+		t.Fatalf("got %d gateway calls, want 1 for a cached track", len(*calls))
+	}
+
+	// a token expiring within the margin must not be cached
+	expire = time.Now().Add(time.Minute).Unix()
+	c.appConfig.MetadataCache = NewMemoryMetadataCache()
+	*calls = nil
+	fetch()
+	fetch()
+	if len(*calls) != 2 {
+		t.Fatalf("got %d gateway calls, want 2 for a track about to expire", len(*calls))
+	}
+}
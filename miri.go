@@ -3,98 +3,160 @@ package miri
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 )
 
-const chunkSize = 2048
+const (
+	chunkSize = 2048
+
+	// verificationTrackID is a track available in most countries, used to check the secret key.
+	verificationTrackID = 3135556
+)
 
 type Client struct {
 	appConfig *Config
+	sessionMu sync.RWMutex
 	session   *Session
+	cache     *diskCache
 }
 
-func (c *Client) getSongContent(ctx context.Context, song *Song, target io.Writer) error {
+func (c *Client) getSongContent(ctx context.Context, song *Song, target io.Writer) (*DownloadResult, error) {
+	if song.Episode != nil {
+		return c.downloadEpisode(ctx, song, target)
+	}
+
+	if res, ok, err := c.copyCachedSong(song.ID, song, target); ok {
+		return res, err
+	}
+
 	quality := c.appConfig.Quality
 
 	media, err := c.fetchMedia(ctx, song, quality)
 	if err != nil {
-		return fmt.Errorf("failed to fetch media: %w", err)
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+
+	mediaFormat := media.GetFormat()
+	if quality != strings.ToLower(mediaFormat) {
+		log.Printf("requested quality '%s' not available, using '%s' instead", quality, strings.ToLower(mediaFormat))
 	}
 
-	stream, err := c.GetMediaStream(ctx, media, song.ID)
+	if c.cache == nil {
+		return c.downloadMedia(ctx, song, media, target)
+	}
+
+	f, err := c.cache.open(ctx, cacheKey(song.ID, mediaFormat), func(w io.Writer) error {
+		_, err := c.downloadMedia(ctx, song, media, w)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get media stream: %w", err)
+		return nil, err
 	}
+	defer f.Close()
 
-	dlCtx, cancel := context.WithTimeout(ctx, c.appConfig.Timeout)
-	defer cancel()
+	return copyCachedFile(f, song, mediaFormat, target)
+}
 
-	mediaFormat := media.GetFormat()
-	key := getKey(c.appConfig.SecretKey, song.ID)
-	if err := c.streamMedia(dlCtx, stream, key, target); err != nil {
-		return fmt.Errorf("failed to stream to target: %w", err)
+// getTrackContent is getSongContent for a track ID, which only fetches the song on a cache miss.
+func (c *Client) getTrackContent(ctx context.Context, trackID int, target io.Writer) error {
+	if _, ok, err := c.copyCachedSong(strconv.Itoa(trackID), nil, target); ok {
+		return err
 	}
 
-	if quality != strings.ToLower(mediaFormat) {
-		log.Printf("requested quality '%s' not available, using '%s' instead", quality, strings.ToLower(mediaFormat))
+	song, err := c.GetSongFromTrackID(ctx, trackID)
+	if err != nil {
+		return fmt.Errorf("failed to get song from track ID: %w", err)
 	}
 
-	return nil
+	_, err = c.getSongContent(ctx, song, target)
+	return err
 }
 
-func (c *Client) streamMedia(ctx context.Context, stream io.ReadCloser, key []byte, target io.Writer) (err error) {
-	defer stream.Close()
+// copyCachedSong copies the best cached format allowed by the configured quality into target,
+// reporting false if none is cached. A lower format may be served even if a better one
+// would now be available, since nothing is asked to Deezer on a hit.
+func (c *Client) copyCachedSong(songID string, song *Song, target io.Writer) (*DownloadResult, bool, error) {
+	if c.cache == nil {
+		return nil, false, nil
+	}
 
-	buffer := make([]byte, chunkSize)
-	for chunk := 0; ; chunk++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// continue
+	for _, format := range qualityFormats(c.appConfig.Quality) {
+		f, err := c.cache.lookup(cacheKey(songID, format))
+		if err != nil {
+			return nil, true, err
 		}
-
-		totalRead := 0
-		for totalRead < chunkSize {
-			n, err := stream.Read(buffer[totalRead:])
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				return err
-			}
-
-			if n > 0 {
-				totalRead += n
-			}
+		if f == nil {
+			continue
 		}
+		defer f.Close()
 
-		if totalRead == 0 {
-			break
-		}
+		res, err := copyCachedFile(f, song, format, target)
+		return res, true, err
+	}
 
-		if chunk%3 == 0 && totalRead == chunkSize {
-			buffer, err = decrypt(buffer, key)
-			if err != nil {
-				return err
-			}
-		}
+	return nil, false, nil
+}
 
-		_, err = target.Write(buffer[:totalRead])
-		if err != nil {
-			return err
-		}
+func copyCachedFile(f *os.File, song *Song, format string, target io.Writer) (*DownloadResult, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
 
-		if totalRead < chunkSize {
-			break
-		}
+	verifier := newDownloadVerifier()
+	if _, err := io.Copy(io.MultiWriter(target, verifier), f); err != nil {
+		return nil, fmt.Errorf("failed to copy cached media: %w", err)
 	}
 
-	return nil
+	return verifier.result(song, format, info.Size())
+}
+
+func (c *Client) downloadMedia(ctx context.Context, song *Song, media *Media, target io.Writer) (*DownloadResult, error) {
+	resp, err := c.openMedia(ctx, media, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media stream: %w", err)
+	}
+
+	dlCtx, cancel := context.WithTimeout(ctx, c.appConfig.Timeout)
+	defer cancel()
+
+	key := getKey(c.appConfig.SecretKey, song.ID)
+	verifier := newDownloadVerifier()
+	if err := c.streamMedia(dlCtx, resp.Body, key, io.MultiWriter(target, verifier), 0); err != nil {
+		return nil, fmt.Errorf("failed to stream to target: %w", err)
+	}
+
+	return verifier.result(song, media.GetFormat(), resp.ContentLength)
+}
+
+// streamMedia decrypts stream into target. firstChunk is the stripe index of the first
+// chunk read from stream, which is not zero when the stream starts mid-file.
+func (c *Client) streamMedia(ctx context.Context, stream io.ReadCloser, key []byte, target io.Writer, firstChunk int) error {
+	defer stream.Close()
+
+	reader := newDecryptingReader(stream, key, firstChunk)
+	_, err := io.Copy(target, &contextReader{ctx: ctx, r: reader})
+	return err
+}
+
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
 }
 
 func (c *Client) getSongsFromTrackID(ctx context.Context, trackID int) (songs []*Song, err error) {
@@ -122,14 +184,8 @@ func (c *Client) GetSongFromTrackID(ctx context.Context, trackID int) (*Song, er
 }
 
 func (c *Client) DownloadTrackByID(ctx context.Context, trackID int) ([]byte, error) {
-	song, err := c.GetSongFromTrackID(ctx, trackID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get song from track ID: %w", err)
-	}
-
 	var buffer bytes.Buffer
-	err = c.getSongContent(ctx, song, &buffer)
-	if err != nil {
+	if err := c.getTrackContent(ctx, trackID, &buffer); err != nil {
 		return nil, fmt.Errorf("failed to get song content: %w", err)
 	}
 
@@ -137,15 +193,20 @@ func (c *Client) DownloadTrackByID(ctx context.Context, trackID int) ([]byte, er
 }
 
 func (c *Client) StreamTrackByID(ctx context.Context, trackID int, target io.Writer) error {
-	song, err := c.GetSongFromTrackID(ctx, trackID)
-	if err != nil {
-		return fmt.Errorf("failed to get songs from track ID: %w", err)
+	if err := c.getTrackContent(ctx, trackID, target); err != nil {
+		return fmt.Errorf("failed to get song content: %w", err)
 	}
 
-	err = c.getSongContent(ctx, song, target)
+	return nil
+}
+
+// StreamSong downloads a song into target, verifying its size and header,
+// and returns the hashes of the decrypted output.
+func (c *Client) StreamSong(ctx context.Context, song *Song, target io.Writer) (*DownloadResult, error) {
+	res, err := c.getSongContent(ctx, song, target)
 	if err != nil {
-		return fmt.Errorf("failed to get song content: %w", err)
+		return nil, fmt.Errorf("failed to get song content: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
@@ -0,0 +1,404 @@
+package miri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// pageSize is the number of songs requested at once from the gateway.
+const pageSize = 1000
+
+var errStopPaging = errors.New("stop paging")
+
+// Songs fetches a resource like FetchResource, but yields its songs page by page
+// instead of keeping them all in memory. The metadata of the resource is populated
+// before the first song is yielded, and its songs are left empty.
+func (c *Client) Songs(ctx context.Context, resource Resource, id int) iter.Seq2[*Song, error] {
+	return func(yield func(*Song, error) bool) {
+		first := 0
+		total, err := c.fetchResourcePage(ctx, resource, id, 0, func(song *Song) bool {
+			first++
+			return yield(song, nil)
+		})
+		if errors.Is(err, errStopPaging) {
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		method, idKey := songListMethod(resource)
+		if method == "" {
+			// the song of a track is part of its metadata
+			for _, song := range resource.GetSongs() {
+				if !yield(song, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		for start := first; start < total; {
+			payload := map[string]any{
+				idKey:   strconv.Itoa(id),
+				"start": start,
+				"nb":    pageSize,
+			}
+
+			n := 0
+			err := c.streamSongList(ctx, method, payload, func(song *Song) bool {
+				n++
+				return yield(song, nil)
+			})
+			if errors.Is(err, errStopPaging) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to fetch songs %d-%d: %w", start, start+pageSize, err))
+				return
+			}
+			if n == 0 {
+				return
+			}
+
+			start += n
+		}
+	}
+}
+
+// songListMethod returns the gateway method listing the songs of a resource, and the name of its ID parameter.
+func songListMethod(resource Resource) (method, idKey string) {
+	switch resource.(type) {
+	case *Playlist:
+		return "playlist.getSongs", "playlist_id"
+	case *Album:
+		return "song.getListByAlbum", "alb_id"
+	case *Artist:
+		return "artist.getTopTrack", "art_id"
+	default:
+		return "", ""
+	}
+}
+
+// Episodes fetches a show like FetchResource, but yields its episodes page by page
+// instead of keeping them all in memory. The metadata of the show is populated
+// before the first episode is yielded, along with the episodes of the first page.
+func (c *Client) Episodes(ctx context.Context, show *Show, id int) iter.Seq2[*EpisodeData, error] {
+	return func(yield func(*EpisodeData, error) bool) {
+		if _, err := c.fetchResourcePage(ctx, show, id, 0, nil); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		first := show.GetEpisodes()
+		for _, episode := range first {
+			if !yield(episode, nil) {
+				return
+			}
+		}
+
+		// deezer.pageShow has no companion list method, so the next pages are further pages of the show
+		for start := len(first); start < show.Results.Episodes.Total; {
+			page := &Show{}
+			if _, err := c.fetchResourcePage(ctx, page, id, start, nil); err != nil {
+				yield(nil, fmt.Errorf("failed to fetch episodes %d-%d: %w", start, start+pageSize, err))
+				return
+			}
+
+			episodes := page.GetEpisodes()
+			if len(episodes) == 0 {
+				return
+			}
+			for _, episode := range episodes {
+				if !yield(episode, nil) {
+					return
+				}
+			}
+
+			start += len(episodes)
+		}
+	}
+}
+
+// fetchResourcePage populates resource with its metadata and decodes the page of songs
+// starting at start, returning the total number of songs it has. The songs are passed to yield
+// as they are decoded, or set on the resource if yield is nil.
+func (c *Client) fetchResourcePage(ctx context.Context, resource Resource, id, start int, yield func(*Song) bool) (int, error) {
+	resourceID := strconv.Itoa(id)
+	payload := map[string]interface{}{
+		"nb":     pageSize,
+		"start":  start,
+		"lang":   "en",
+		"tab":    0,
+		"tags":   true,
+		"header": true,
+	}
+	switch r := resource.(type) {
+	case *Playlist:
+		payload["playlist_id"] = resourceID
+	case *Album:
+		payload["alb_id"] = resourceID
+	case *Artist:
+		payload["art_id"] = resourceID
+	case *Track:
+		payload["sng_id"] = resourceID
+	case *Episode:
+		payload["episode_id"] = resourceID
+	case *Show:
+		payload["show_id"] = resourceID
+		payload["user_id"] = c.User().ID
+	default:
+		return 0, fmt.Errorf("unsupported resource type: %T", r)
+	}
+
+	var total int
+	err := c.withSession(ctx, func(session *Session) error {
+		method := "deezer.page" + resource.GetType()
+		body, err := c.openGateway(ctx, session, method, payload)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		total, err = decodePage(body, method, resource, yield)
+		return err
+	})
+
+	return total, err
+}
+
+// decodePage walks a {"error":...,"results":{"DATA":...,"SONGS":{"data":[...],"total":...}}} response
+// without buffering its songs, which are under "TOP" for artists. The rest of the results
+// is unmarshalled into resource, before the first song is passed to yield.
+func decodePage(r io.Reader, method string, resource Resource, yield func(*Song) bool) (int, error) {
+	var songs []*Song
+	keep := yield == nil
+	if keep {
+		yield = func(song *Song) bool {
+			songs = append(songs, song)
+			return true
+		}
+	}
+
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+
+	results := make(map[string]json.RawMessage)
+	total, found := 0, false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+
+		switch key {
+		case "error":
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return 0, err
+			}
+			if err := pageError(raw, method); err != nil {
+				return 0, err
+			}
+		case "results":
+			if err := expectDelim(dec, '{'); err != nil {
+				return 0, err
+			}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return 0, err
+				}
+				name, _ := tok.(string)
+				found = true
+
+				if name == "SONGS" || name == "TOP" {
+					if err := unmarshalResults(resource, results); err != nil {
+						return 0, err
+					}
+					n, err := decodeSongResults(dec, yield)
+					if err != nil {
+						return 0, err
+					}
+					total = max(total, n)
+					continue
+				}
+
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return 0, err
+				}
+				results[name] = raw
+			}
+			if err := expectDelim(dec, '}'); err != nil {
+				return 0, err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("unexpected response")
+	}
+	if err := unmarshalResults(resource, results); err != nil {
+		return 0, err
+	}
+	if keep {
+		resource.SetSongs(songs)
+	}
+
+	return total, nil
+}
+
+// unmarshalResults populates resource with the results of a page other than its songs.
+func unmarshalResults(resource Resource, results map[string]json.RawMessage) error {
+	data, err := json.Marshal(map[string]any{"results": results})
+	if err != nil {
+		return err
+	}
+
+	return resource.Unmarshal(data)
+}
+
+// pageError converts the error field of a page response, naming the resource on invalid IDs.
+func pageError(raw json.RawMessage, method string) error {
+	err := gatewayError(raw)
+
+	var gwErr *GatewayError
+	if errors.As(err, &gwErr) {
+		gwErr.Method = method
+		if kind, ok := strings.CutSuffix(gwErr.Message, "::getData"); ok && gwErr.Code == "DATA_ERROR" {
+			if kind == "song" {
+				kind = "track"
+			}
+			return fmt.Errorf("invalid %s ID: %w", kind, err)
+		}
+	}
+
+	return err
+}
+
+// streamSongList calls a gateway method returning a list of songs, decoding them one by one.
+// It returns errStopPaging if yield asks to stop.
+func (c *Client) streamSongList(ctx context.Context, method string, payload any, yield func(*Song) bool) error {
+	return c.withSession(ctx, func(session *Session) error {
+		body, err := c.openGateway(ctx, session, method, payload)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		return decodeSongList(body, yield)
+	})
+}
+
+// decodeSongList walks a {"error":...,"results":{"data":[...]}} response without buffering it.
+func decodeSongList(r io.Reader, yield func(*Song) bool) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "error":
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if err := gatewayError(raw); err != nil {
+				return err
+			}
+		case "results":
+			if _, err := decodeSongResults(dec, yield); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeSongResults walks a {"data":[...],"total":...} object, returning its total.
+func decodeSongResults(dec *json.Decoder, yield func(*Song) bool) (int, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+
+		switch key {
+		case "data":
+			err = decodeSongArray(dec, yield)
+		case "total":
+			err = dec.Decode(&total)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return total, expectDelim(dec, '}')
+}
+
+func decodeSongArray(dec *json.Decoder, yield func(*Song) bool) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		var song Song
+		if err := dec.Decode(&song); err != nil {
+			return err
+		}
+		if !yield(&song) {
+			return errStopPaging
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("unexpected token %v, expected %v", tok, delim)
+	}
+
+	return nil
+}
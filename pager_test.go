@@ -0,0 +1,160 @@
+package miri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestdata(t *testing.T, name string) *os.File {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+func TestDecodeSongList(t *testing.T) {
+	var titles []string
+	err := decodeSongList(openTestdata(t, "playlist_getsongs.json"), func(song *Song) bool {
+		titles = append(titles, song.Title)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Harder, Better, Faster, Stronger", "Without Me", "In Da Club"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", titles, want)
+	}
+}
+
+func TestDecodeSongListStop(t *testing.T) {
+	n := 0
+	err := decodeSongList(openTestdata(t, "playlist_getsongs.json"), func(song *Song) bool {
+		n++
+		return false
+	})
+	if !errors.Is(err, errStopPaging) || n != 1 {
+		t.Fatalf("got %v after %d songs, want %v after 1", err, n, errStopPaging)
+	}
+}
+
+func TestDecodeSongListError(t *testing.T) {
+	body := `{"error":{"DATA_ERROR":"playlist::getSongs"},"results":{}}`
+	err := decodeSongList(strings.NewReader(body), func(*Song) bool { return true })
+
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) || gwErr.Code != "DATA_ERROR" {
+		t.Fatalf("got %v, want a DATA_ERROR", err)
+	}
+}
+
+func TestDecodeSongResults(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"data":[{"SNG_ID":"1"},{"SNG_ID":"2"}],"count":2,"total":7,"filtered_count":0}`))
+
+	var ids []string
+	total, err := decodeSongResults(dec, func(song *Song) bool {
+		ids = append(ids, song.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 7 || strings.Join(ids, ",") != "1,2" {
+		t.Fatalf("got %v songs of %d, want [1 2] of 7", ids, total)
+	}
+}
+
+func TestDecodePage(t *testing.T) {
+	playlist := &Playlist{}
+	var titles []string
+	total, err := decodePage(openTestdata(t, "playlist.json"), "deezer.pagePlaylist", playlist, func(song *Song) bool {
+		if playlist.Results.Data.Title == "" {
+			t.Error("song yielded before the metadata")
+		}
+		titles = append(titles, song.Title)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(titles) != 2 {
+		t.Fatalf("got %d of %d songs, want 2 of 2", len(titles), total)
+	}
+	if len(playlist.GetSongs()) != 0 {
+		t.Fatal("yielded songs were also kept in the playlist")
+	}
+
+	artist := &Artist{}
+	total, err = decodePage(openTestdata(t, "artist.json"), "deezer.pageArtist", artist, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total == 0 || len(artist.GetSongs()) == 0 {
+		t.Fatalf("got %d of %d top songs, want them kept in the artist", len(artist.GetSongs()), total)
+	}
+}
+
+func TestDecodePageError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"invalid ID", `{"error":{"DATA_ERROR":"playlist::getData"},"results":{}}`, "invalid playlist ID"},
+		{"invalid track", `{"error":{"DATA_ERROR":"song::getData"},"results":{}}`, "invalid track ID"},
+		{"expired session", `{"error":{"VALID_TOKEN_REQUIRED":"Invalid CSRF token"},"results":{}}`, ErrSessionExpired.Error()},
+		{"empty results", `{"error":[],"results":{}}`, "unexpected response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodePage(strings.NewReader(tt.body), "deezer.pagePlaylist", &Playlist{}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSongsPaging(t *testing.T) {
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		var res struct {
+			Results map[string]any `json:"results"`
+		}
+		switch call.Method {
+		case "deezer.pagePlaylist":
+			json.NewDecoder(openTestdata(t, "playlist.json")).Decode(&res)
+			res.Results["SONGS"].(map[string]any)["total"] = 5
+		case "playlist.getSongs":
+			json.NewDecoder(openTestdata(t, "playlist_getsongs.json")).Decode(&res)
+		}
+		return res.Results, nil
+	})
+
+	playlist := &Playlist{}
+	n := 0
+	for _, err := range c.Songs(context.Background(), playlist, 908622995) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+
+	if n != 5 {
+		t.Fatalf("got %d songs, want 5", n)
+	}
+	if len(*calls) != 2 || (*calls)[1].Payload["start"] != float64(2) {
+		t.Fatalf("unexpected calls: %+v", *calls)
+	}
+}
@@ -9,10 +9,12 @@ import (
 type Playlist struct {
 	Results struct {
 		Data struct {
+			ID       string `json:"PLAYLIST_ID"`
 			Title    string `json:"TITLE"`
 			Status   int    `json:"STATUS"`
 			Creator  string `json:"PARENT_USERNAME"`
 			Duration int    `json:"DURATION"`
+			Picture  string `json:"PLAYLIST_PICTURE"`
 		} `json:"DATA"`
 		Songs struct {
 			Data []*Song `json:"data"`
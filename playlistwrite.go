@@ -0,0 +1,133 @@
+package miri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Playlist statuses, as sent to playlist.create and returned in Playlist.Results.Data.Status.
+const (
+	PlaylistPublic        = 0
+	PlaylistPrivate       = 1
+	PlaylistCollaborative = 2
+)
+
+// ErrPlaylistNotModified is returned when the gateway accepts a playlist change but reports it did not apply it.
+var ErrPlaylistNotModified = errors.New("playlist not modified")
+
+// CreatePlaylist creates a playlist owned by the current user, optionally filled with songs, and returns its ID.
+func (c *Client) CreatePlaylist(ctx context.Context, title, description string, status int, songIDs ...int) (int, error) {
+	if title == "" {
+		return 0, fmt.Errorf("playlist title cannot be empty")
+	}
+
+	payload := map[string]any{
+		"title":       title,
+		"description": description,
+		"status":      status,
+		"songs":       playlistSongs(songIDs),
+	}
+
+	var playlistID flexInt
+	if err := c.callGateway(ctx, "playlist.create", payload, &playlistID); err != nil {
+		return 0, fmt.Errorf("failed to create playlist: %w", err)
+	}
+
+	if playlistID == 0 {
+		return 0, fmt.Errorf("failed to create playlist: no ID returned")
+	}
+
+	return int(playlistID), nil
+}
+
+// AddSongsToPlaylist appends songs to the end of a playlist.
+func (c *Client) AddSongsToPlaylist(ctx context.Context, playlistID int, songIDs ...int) error {
+	payload := map[string]any{
+		"playlist_id": strconv.Itoa(playlistID),
+		"songs":       playlistSongs(songIDs),
+		"offset":      -1,
+	}
+
+	if err := c.updatePlaylist(ctx, "playlist.addSongs", playlistID, payload); err != nil {
+		return fmt.Errorf("failed to add songs to playlist %d: %w", playlistID, err)
+	}
+
+	return nil
+}
+
+// RemoveSongsFromPlaylist removes songs from a playlist.
+func (c *Client) RemoveSongsFromPlaylist(ctx context.Context, playlistID int, songIDs ...int) error {
+	payload := map[string]any{
+		"playlist_id": strconv.Itoa(playlistID),
+		"songs":       playlistSongs(songIDs),
+	}
+
+	if err := c.updatePlaylist(ctx, "playlist.deleteSongs", playlistID, payload); err != nil {
+		return fmt.Errorf("failed to remove songs from playlist %d: %w", playlistID, err)
+	}
+
+	return nil
+}
+
+// ReorderPlaylist sets the order of the songs of a playlist. songIDs must list all of them.
+func (c *Client) ReorderPlaylist(ctx context.Context, playlistID int, songIDs ...int) error {
+	order := make([]string, len(songIDs))
+	for i, id := range songIDs {
+		order[i] = strconv.Itoa(id)
+	}
+
+	payload := map[string]any{
+		"playlist_id": strconv.Itoa(playlistID),
+		"order":       order,
+	}
+
+	if err := c.updatePlaylist(ctx, "playlist.updateOrder", playlistID, payload); err != nil {
+		return fmt.Errorf("failed to reorder playlist %d: %w", playlistID, err)
+	}
+
+	return nil
+}
+
+// DeletePlaylist deletes a playlist owned by the current user.
+func (c *Client) DeletePlaylist(ctx context.Context, playlistID int) error {
+	payload := map[string]any{
+		"playlist_id": strconv.Itoa(playlistID),
+	}
+
+	if err := c.updatePlaylist(ctx, "playlist.delete", playlistID, payload); err != nil {
+		return fmt.Errorf("failed to delete playlist %d: %w", playlistID, err)
+	}
+
+	return nil
+}
+
+// updatePlaylist calls a gateway method that returns whether the change was applied,
+// dropping the cached copy of the playlist once it was.
+func (c *Client) updatePlaylist(ctx context.Context, method string, playlistID int, payload any) error {
+	var ok flexBool
+	if err := c.callGateway(ctx, method, payload, &ok); err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrPlaylistNotModified
+	}
+
+	if cache := c.appConfig.MetadataCache; cache != nil {
+		cache.Delete("Playlist", playlistID)
+	}
+
+	return nil
+}
+
+// playlistSongs converts song IDs to the [[id, position]] pairs expected by the gateway.
+func playlistSongs(songIDs []int) [][2]int {
+	songs := make([][2]int, len(songIDs))
+	for i, id := range songIDs {
+		songs[i] = [2]int{id, 0}
+	}
+
+	return songs
+}
@@ -0,0 +1,118 @@
+package miri
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCreatePlaylist(t *testing.T) {
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return "13459874", nil
+	})
+
+	id, err := c.CreatePlaylist(t.Context(), "Road trip", "", PlaylistPrivate, 3135556, 1109731)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 13459874 {
+		t.Fatalf("got playlist ID %d, want 13459874", id)
+	}
+
+	call := (*calls)[0]
+	if call.Method != "playlist.create" || call.Payload["title"] != "Road trip" || call.Payload["status"] != float64(PlaylistPrivate) {
+		t.Fatalf("unexpected call: %+v", call)
+	}
+	songs, _ := call.Payload["songs"].([]any)
+	if len(songs) != 2 {
+		t.Fatalf("got songs %v, want two [id, position] pairs", call.Payload["songs"])
+	}
+	if pair, _ := songs[1].([]any); len(pair) != 2 || pair[0] != float64(1109731) {
+		t.Fatalf("got song %v, want [1109731, 0]", songs[1])
+	}
+}
+
+func TestCreatePlaylistErrors(t *testing.T) {
+	c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return 0, nil
+	})
+
+	if _, err := c.CreatePlaylist(t.Context(), "", "", PlaylistPublic); err == nil {
+		t.Fatal("expected an error for an empty title")
+	}
+	if len(*calls) != 0 {
+		t.Fatal("the gateway must not be called for an empty title")
+	}
+
+	if _, err := c.CreatePlaylist(t.Context(), "Empty", "", PlaylistPublic); err == nil {
+		t.Fatal("expected an error when no ID is returned")
+	}
+}
+
+func TestUpdatePlaylist(t *testing.T) {
+	tests := []struct {
+		name    string
+		results any
+		want    error
+	}{
+		{"applied", true, nil},
+		{"applied as number", 1, nil},
+		{"not applied", false, ErrPlaylistNotModified},
+		{"not applied as string", "0", ErrPlaylistNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, calls := newGatewayClient(t, func(call gatewayCall) (any, any) {
+				return tt.results, nil
+			})
+
+			err := c.AddSongsToPlaylist(t.Context(), 908622995, 3135556)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			call := (*calls)[0]
+			if call.Method != "playlist.addSongs" || call.Payload["playlist_id"] != "908622995" {
+				t.Fatalf("unexpected call: %+v", call)
+			}
+		})
+	}
+}
+
+func TestPlaylistWriteInvalidatesCache(t *testing.T) {
+	applied := true
+	c, _ := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return applied, nil
+	})
+	cache := NewMemoryMetadataCache()
+	c.appConfig.MetadataCache = cache
+
+	writes := map[string]func() error{
+		"add":     func() error { return c.AddSongsToPlaylist(t.Context(), 42, 1) },
+		"remove":  func() error { return c.RemoveSongsFromPlaylist(t.Context(), 42, 1) },
+		"reorder": func() error { return c.ReorderPlaylist(t.Context(), 42, 2, 1) },
+		"delete":  func() error { return c.DeletePlaylist(t.Context(), 42) },
+	}
+
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			applied = false
+			cache.Set("Playlist", 42, []byte("{}"), time.Hour)
+			if err := write(); !errors.Is(err, ErrPlaylistNotModified) {
+				t.Fatalf("got %v, want %v", err, ErrPlaylistNotModified)
+			}
+			if _, ok := cache.Get("Playlist", 42); !ok {
+				t.Fatal("a change that was not applied dropped the cached playlist")
+			}
+
+			applied = true
+			if err := write(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := cache.Get("Playlist", 42); ok {
+				t.Fatal("the cached playlist survived the change")
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package miri
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Episode is a podcast episode, loaded through deezer.pageEpisode.
+type Episode struct {
+	Results struct {
+		Data *EpisodeData `json:"DATA"`
+	} `json:"results"`
+}
+
+// Show is a podcast, loaded through deezer.pageShow along with its latest episodes.
+type Show struct {
+	Results struct {
+		Data struct {
+			ID          string `json:"SHOW_ID"`
+			Name        string `json:"SHOW_NAME"`
+			Description string `json:"SHOW_DESCRIPTION"`
+			Picture     string `json:"SHOW_ART_MD5"`
+		} `json:"DATA"`
+		Episodes struct {
+			Data  []*EpisodeData `json:"data"`
+			Total int            `json:"total"`
+		} `json:"EPISODES"`
+	} `json:"results"`
+}
+
+type EpisodeData struct {
+	ID              string    `json:"EPISODE_ID"`
+	Title           string    `json:"EPISODE_TITLE"`
+	Description     string    `json:"EPISODE_DESCRIPTION"`
+	ShowID          string    `json:"SHOW_ID"`
+	ShowName        string    `json:"SHOW_NAME"`
+	Picture         string    `json:"SHOW_ART_MD5"`
+	Duration        string    `json:"DURATION"`
+	PublishedAt     time.Time `json:"EPISODE_PUBLISHED_TIMESTAMP"`
+	DirectStreamURL string    `json:"EPISODE_DIRECT_STREAM_URL"` // plain, unencrypted audio
+}
+
+func (e *EpisodeData) UnmarshalJSON(data []byte) error {
+	type Alias EpisodeData
+	aux := struct {
+		*Alias
+		Duration    flexString `json:"DURATION"`
+		PublishedAt flexDate   `json:"EPISODE_PUBLISHED_TIMESTAMP"`
+	}{Alias: (*Alias)(e)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	e.Duration = string(aux.Duration)
+	e.PublishedAt = time.Time(aux.PublishedAt)
+
+	return nil
+}
+
+func (e *EpisodeData) GetTitle() string {
+	return e.Title
+}
+
+// Song represents the episode as a song, the show standing in for the artist,
+// so that it can be listed, named and downloaded like one.
+func (e *EpisodeData) Song() *Song {
+	duration, _ := parseNumber(e.Duration)
+
+	return &Song{
+		ID:              e.ID,
+		Title:           e.Title,
+		Artist:          e.ShowName,
+		ArtistID:        e.ShowID,
+		Cover:           e.Picture,
+		Duration:        e.Duration,
+		DurationSeconds: int(duration),
+		PhysicalRelease: e.PublishedAt,
+		Episode:         e,
+	}
+}
+
+// DurationTime returns the duration of the episode, or zero if unknown.
+func (e *EpisodeData) DurationTime() time.Duration {
+	seconds, _ := parseNumber(e.Duration)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func (e *Episode) String() string {
+	if e.Results.Data == nil {
+		return "Episode: No data available"
+	}
+
+	return fmt.Sprintf(
+		`================ [ Episode Info ] ================
+Title:    %s
+Show:     %s
+Duration: %s
+==================================================`,
+		e.Results.Data.Title,
+		e.Results.Data.ShowName,
+		e.Results.Data.DurationTime(),
+	)
+}
+
+func (e *Episode) GetType() string {
+	return "Episode"
+}
+
+func (e *Episode) GetTitle() string {
+	if e.Results.Data == nil {
+		return ""
+	}
+	return e.Results.Data.Title
+}
+
+// GetSongs returns the episode as a song, see EpisodeData.Song.
+func (e *Episode) GetSongs() []*Song {
+	return episodeSongs(e.GetEpisodes())
+}
+
+func (e *Episode) SetSongs(songs []*Song) {}
+
+func (e *Episode) GetEpisodes() []*EpisodeData {
+	if e.Results.Data == nil {
+		return []*EpisodeData{}
+	}
+	return []*EpisodeData{e.Results.Data}
+}
+
+func (e *Episode) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, e)
+}
+
+func (s *Show) String() string {
+	return fmt.Sprintf(
+		`================= [ Show Info ] ==================
+Title:    %s
+Episodes: %d
+==================================================`,
+		s.Results.Data.Name,
+		len(s.Results.Episodes.Data),
+	)
+}
+
+func (s *Show) GetType() string {
+	return "Show"
+}
+
+func (s *Show) GetTitle() string {
+	return s.Results.Data.Name
+}
+
+// GetSongs returns the episodes of the show as songs, see EpisodeData.Song.
+func (s *Show) GetSongs() []*Song {
+	return episodeSongs(s.GetEpisodes())
+}
+
+func (s *Show) SetSongs(songs []*Song) {}
+
+func (s *Show) GetEpisodes() []*EpisodeData {
+	return s.Results.Episodes.Data
+}
+
+func (s *Show) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, s)
+}
+
+func episodeSongs(episodes []*EpisodeData) []*Song {
+	songs := make([]*Song, len(episodes))
+	for i, e := range episodes {
+		songs[i] = e.Song()
+	}
+
+	return songs
+}
+
+// GetEpisode fetches a podcast episode by ID.
+func (c *Client) GetEpisode(ctx context.Context, episodeID int) (*EpisodeData, error) {
+	episode := &Episode{}
+	if err := c.FetchResource(ctx, episode, episodeID); err != nil {
+		return nil, err
+	}
+
+	if episode.Results.Data == nil {
+		return nil, fmt.Errorf("no episode found for ID: %d", episodeID)
+	}
+
+	return episode.Results.Data, nil
+}
+
+func (c *Client) DownloadEpisodeByID(ctx context.Context, episodeID int) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := c.StreamEpisodeByID(ctx, episodeID, &buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (c *Client) StreamEpisodeByID(ctx context.Context, episodeID int, target io.Writer) error {
+	episode, err := c.GetEpisode(ctx, episodeID)
+	if err != nil {
+		return fmt.Errorf("failed to get episode: %w", err)
+	}
+
+	if _, err := c.StreamEpisode(ctx, episode, target); err != nil {
+		return fmt.Errorf("failed to get episode content: %w", err)
+	}
+
+	return nil
+}
+
+// StreamEpisode downloads an episode into target, like StreamSong does with its Song.
+func (c *Client) StreamEpisode(ctx context.Context, episode *EpisodeData, target io.Writer) (*DownloadResult, error) {
+	return c.StreamSong(ctx, episode.Song(), target)
+}
+
+// downloadEpisode downloads the song of an episode. Episodes are served as-is,
+// without the stripe cipher used for songs, and are not cached.
+// The download is only aborted when no data is received for the configured timeout.
+func (c *Client) downloadEpisode(ctx context.Context, song *Song, target io.Writer) (*DownloadResult, error) {
+	episode := song.Episode
+	if episode.DirectStreamURL == "" {
+		return nil, fmt.Errorf("episode %s has no direct stream URL", episode.ID)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader := &idleReader{timeout: c.appConfig.Timeout}
+	if reader.timeout > 0 {
+		reader.timer = time.AfterFunc(reader.timeout, cancel)
+		defer reader.timer.Stop()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", episode.DirectStreamURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	streamingClient := *c.Session().HttpClient
+	streamingClient.Timeout = 0
+
+	resp, err := streamingClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	reader.r = resp.Body
+	verifier := newDownloadVerifier()
+	if _, err := io.Copy(io.MultiWriter(target, verifier), reader); err != nil {
+		return nil, fmt.Errorf("failed to stream to target: %w", err)
+	}
+
+	format := "MP3"
+	if strings.Contains(resp.Header.Get("Content-Type"), "flac") {
+		format = "FLAC"
+	}
+
+	return verifier.result(song, format, resp.ContentLength)
+}
+
+// idleReader pushes back its timer, which aborts the download, every time data is read.
+type idleReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (i *idleReader) Read(p []byte) (int, error) {
+	n, err := i.r.Read(p)
+	if n > 0 && i.timer != nil {
+		i.timer.Reset(i.timeout)
+	}
+
+	return n, err
+}
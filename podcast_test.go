@@ -0,0 +1,79 @@
+package miri
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShowSongs(t *testing.T) {
+	show := &Show{}
+	show.Results.Data.Name = "Show"
+	show.Results.Episodes.Data = []*EpisodeData{
+		{ID: "1", Title: "First", ShowName: "Show", Picture: "abc", Duration: "60"},
+		{ID: "2", Title: "Second", ShowName: "Show"},
+	}
+
+	songs := show.GetSongs()
+	if len(songs) != 2 {
+		t.Fatalf("got %d songs, want 2", len(songs))
+	}
+
+	s := songs[0]
+	if s.ID != "1" || s.GetTitle() != "First" || s.Artist != "Show" || s.DurationTime() != time.Minute {
+		t.Fatalf("unexpected song: %+v", s)
+	}
+	if s.Episode != show.Results.Episodes.Data[0] {
+		t.Fatal("song does not point to its episode")
+	}
+	if !strings.Contains(s.CoverURL(100), "/talk/abc/") {
+		t.Fatalf("got cover %s, want the show picture", s.CoverURL(100))
+	}
+}
+
+// TestStreamEpisodeIdleTimeout checks that slow downloads are only aborted when they stall.
+func TestStreamEpisodeIdleTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		for range 6 {
+			w.Write([]byte("data"))
+			w.(http.Flusher).Flush()
+
+			pause := timeout / 2
+			if r.URL.Path == "/stalled" {
+				pause = 5 * timeout
+			}
+			select {
+			case <-time.After(pause):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		appConfig: &Config{Timeout: timeout},
+		session:   &Session{HttpClient: srv.Client()},
+	}
+
+	var buf bytes.Buffer
+	res, err := c.StreamEpisode(context.Background(), &EpisodeData{ID: "1", DirectStreamURL: srv.URL + "/slow"}, &buf)
+	if err != nil {
+		t.Fatalf("slow download failed: %v", err)
+	}
+	if buf.Len() != 24 || res.Format != "MP3" || res.Song.Episode == nil {
+		t.Fatalf("got %d bytes in %s for %+v", buf.Len(), res.Format, res.Song)
+	}
+
+	_, err = c.StreamEpisode(context.Background(), &EpisodeData{ID: "1", DirectStreamURL: srv.URL + "/stalled"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("stalled download succeeded")
+	}
+}
@@ -0,0 +1,225 @@
+package miri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// accountCooldown is how long an account is deprioritized after hitting its quota or losing its license.
+const accountCooldown = 5 * time.Minute
+
+var (
+	// ErrGeoBlocked is returned when a track is not available with the license of the account.
+	ErrGeoBlocked = errors.New("track not available for this account")
+	// ErrQuotaExceeded is returned when Deezer rate limits the account.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrNoAccounts is returned by an empty Pool.
+	ErrNoAccounts = errors.New("no accounts available")
+)
+
+type PoolStrategy int
+
+const (
+	RoundRobin PoolStrategy = iota // cycle through the accounts
+	LeastBusy                      // pick the account with the fewest requests in flight
+)
+
+// AccountStats holds the health statistics of an account in a Pool.
+type AccountStats struct {
+	Index       int // position of the account in the pool
+	InFlight    int64
+	Requests    int64
+	Failures    int64
+	Failovers   int64 // failures that caused the request to move to another account
+	LastError   error
+	LastErrorAt time.Time
+	CoolingDown bool
+}
+
+// Pool spreads requests over several authenticated clients, failing over to the next one
+// when an account is geo-blocked, has an expired license or hits its quota.
+type Pool struct {
+	Strategy PoolStrategy
+
+	accounts []*poolAccount
+	next     atomic.Uint64
+}
+
+type poolAccount struct {
+	index    int
+	client   *Client
+	inFlight atomic.Int64
+
+	mu         sync.Mutex
+	stats      AccountStats
+	coolingEnd time.Time
+}
+
+func NewPool(strategy PoolStrategy, clients ...*Client) *Pool {
+	p := &Pool{Strategy: strategy}
+	for i, c := range clients {
+		p.accounts = append(p.accounts, &poolAccount{index: i, client: c, stats: AccountStats{Index: i}})
+	}
+
+	return p
+}
+
+// Do calls fn with one client after the other, in the order given by the strategy,
+// until it succeeds or fails with an error that another account would not solve.
+func (p *Pool) Do(ctx context.Context, fn func(c *Client) error) error {
+	accounts := p.order()
+	if len(accounts) == 0 {
+		return ErrNoAccounts
+	}
+
+	var errs []error
+	for _, a := range accounts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := a.do(fn)
+		if err == nil {
+			return nil
+		}
+		if !isFailoverError(err) {
+			return err
+		}
+
+		a.failover(err)
+		errs = append(errs, fmt.Errorf("account %d: %w", a.index, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stats returns the health statistics of every account.
+func (p *Pool) Stats() []AccountStats {
+	stats := make([]AccountStats, len(p.accounts))
+	for i, a := range p.accounts {
+		a.mu.Lock()
+		stats[i] = a.stats
+		stats[i].CoolingDown = time.Now().Before(a.coolingEnd)
+		a.mu.Unlock()
+		stats[i].InFlight = a.inFlight.Load()
+	}
+
+	return stats
+}
+
+func (p *Pool) GetSongFromTrackID(ctx context.Context, trackID int) (song *Song, err error) {
+	err = p.Do(ctx, func(c *Client) error {
+		song, err = c.GetSongFromTrackID(ctx, trackID)
+		return err
+	})
+
+	return song, err
+}
+
+func (p *Pool) DownloadTrackByID(ctx context.Context, trackID int) (data []byte, err error) {
+	err = p.Do(ctx, func(c *Client) error {
+		data, err = c.DownloadTrackByID(ctx, trackID)
+		return err
+	})
+
+	return data, err
+}
+
+// StreamTrackByID streams a track into target. Once some data was written,
+// the request can no longer fail over to another account.
+func (p *Pool) StreamTrackByID(ctx context.Context, trackID int, target io.Writer) error {
+	return p.Do(ctx, func(c *Client) error {
+		cw := &countingWriter{w: target}
+		err := c.StreamTrackByID(ctx, trackID, cw)
+		if err != nil && cw.n > 0 && isFailoverError(err) {
+			return fmt.Errorf("stream interrupted after %d bytes: %v", cw.n, err)
+		}
+		return err
+	})
+}
+
+// order returns the accounts in the order they should be tried. Accounts cooling down go last.
+func (p *Pool) order() []*poolAccount {
+	n := len(p.accounts)
+	if n == 0 {
+		return nil
+	}
+
+	accounts := make([]*poolAccount, n)
+	start := int(p.next.Add(1)-1) % n
+	for i := range n {
+		accounts[i] = p.accounts[(start+i)%n]
+	}
+
+	if p.Strategy == LeastBusy {
+		sort.SliceStable(accounts, func(i, j int) bool {
+			return accounts[i].inFlight.Load() < accounts[j].inFlight.Load()
+		})
+	}
+
+	now := time.Now()
+	sort.SliceStable(accounts, func(i, j int) bool {
+		return !accounts[i].coolingDown(now) && accounts[j].coolingDown(now)
+	})
+
+	return accounts
+}
+
+func (a *poolAccount) do(fn func(c *Client) error) error {
+	a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+
+	err := fn(a.client)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats.Requests++
+	if err != nil {
+		a.stats.Failures++
+		a.stats.LastError = err
+		a.stats.LastErrorAt = time.Now()
+	}
+
+	return err
+}
+
+func (a *poolAccount) failover(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats.Failovers++
+
+	// geo-blocking depends on the track, not on the account
+	if !errors.Is(err, ErrGeoBlocked) {
+		a.coolingEnd = time.Now().Add(accountCooldown)
+	}
+}
+
+func (a *poolAccount) coolingDown(now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return now.Before(a.coolingEnd)
+}
+
+func isFailoverError(err error) bool {
+	return errors.Is(err, ErrGeoBlocked) || errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrSessionExpired)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,5 +1,7 @@
 package miri
 
+import "fmt"
+
 type Resource interface {
 	GetTitle() string
 	GetType() string
@@ -7,3 +9,24 @@ type Resource interface {
 	SetSongs(songs []*Song)
 	Unmarshal(data []byte) error
 }
+
+// NewResource returns an empty resource of the given kind:
+// "track", "album", "playlist", "artist", "episode" or "show".
+func NewResource(kind string) (Resource, error) {
+	switch kind {
+	case "track":
+		return &Track{}, nil
+	case "album":
+		return &Album{}, nil
+	case "playlist":
+		return &Playlist{}, nil
+	case "artist":
+		return &Artist{}, nil
+	case "episode":
+		return &Episode{}, nil
+	case "show":
+		return &Show{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resource kind: %s", kind)
+	}
+}
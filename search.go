@@ -101,6 +101,16 @@ func (opt *SearchOptions) Validate() error {
 
 // SearchTracks searches for tracks on Deezer matching the given query.
 func SearchTracks(ctx context.Context, opt SearchOptions) (results []SongResult, err error) {
+	return searchTracks(ctx, opt, "")
+}
+
+// SearchTracks searches for tracks like the package-level SearchTracks,
+// authenticating with the configured OAuth access token if any.
+func (c *Client) SearchTracks(ctx context.Context, opt SearchOptions) (results []SongResult, err error) {
+	return searchTracks(ctx, opt, c.appConfig.AccessToken)
+}
+
+func searchTracks(ctx context.Context, opt SearchOptions, accessToken string) (results []SongResult, err error) {
 	err = opt.Validate()
 	if err != nil {
 		return nil, err
@@ -117,6 +127,9 @@ func SearchTracks(ctx context.Context, opt SearchOptions) (results []SongResult,
 	} else {
 		p.Set("strict", "off")
 	}
+	if accessToken != "" {
+		p.Set("access_token", accessToken)
+	}
 
 	url := fmt.Sprintf("%s%s/%s?%s", deezerAPIBase, endpointSearch, endpointTrack, p.Encode())
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -0,0 +1,189 @@
+// Package server serves decrypted tracks over HTTP.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/birabittoh/miri"
+)
+
+var errLimitReached = errors.New("limit reached")
+
+// Handler serves decrypted tracks at /tracks/{id}, with support for single byte ranges.
+type Handler struct {
+	client *miri.Client
+	mux    *http.ServeMux
+}
+
+func NewHandler(client *miri.Client) *Handler {
+	h := &Handler{client: client, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /tracks/{id}", h.serveTrack)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveTrack(w http.ResponseWriter, r *http.Request) {
+	trackID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	rng, partial := parseRange(r.Header.Get("Range"))
+	if partial && rng.suffix == 0 && rng.start < 0 {
+		http.Error(w, "invalid range", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	start, end := rng.start, rng.end
+	if rng.suffix > 0 {
+		start = 0
+	}
+
+	stream, err := h.client.OpenTrackByID(r.Context(), trackID, start)
+	if err == nil && rng.suffix > 0 && stream.Size > rng.suffix {
+		// the size is only known once the stream is open, so reopen it at the suffix
+		start = stream.Size - rng.suffix
+		stream.Close()
+		stream, err = h.client.OpenTrackByID(r.Context(), trackID, start)
+	}
+	if err == nil && partial && stream.Size < 0 {
+		// without the size no range can be computed, so serve the whole track instead
+		partial = false
+		if start > 0 {
+			stream.Close()
+			stream, err = h.client.OpenTrackByID(r.Context(), trackID, 0)
+		}
+	}
+	if err != nil {
+		writeOpenError(w, err)
+		return
+	}
+	defer stream.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", stream.ContentType())
+	header.Set("Accept-Ranges", "bytes")
+
+	var target io.Writer = w
+	status := http.StatusOK
+	if partial {
+		if end < 0 || end >= stream.Size {
+			end = stream.Size - 1
+		}
+
+		length := end - start + 1
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, stream.Size))
+		header.Set("Content-Length", strconv.FormatInt(length, 10))
+		target = &limitWriter{w: w, n: length}
+		status = http.StatusPartialContent
+	} else if stream.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(stream.Size, 10))
+	}
+
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := stream.WriteTo(target); err != nil && !errors.Is(err, errLimitReached) {
+		log.Printf("failed to stream track %d: %v", trackID, err)
+	}
+}
+
+func writeOpenError(w http.ResponseWriter, err error) {
+	var rangeErr *miri.RangeError
+	if errors.As(err, &rangeErr) {
+		if rangeErr.Size >= 0 {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", rangeErr.Size))
+		}
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+// byteRange is a single range of a Range header: either start-end, with end -1 when
+// open-ended, or the last suffix bytes.
+type byteRange struct {
+	start, end, suffix int64
+}
+
+// parseRange parses a single "bytes=start-end" or "bytes=-suffix" range.
+// It reports false for headers it can't parse or doesn't support, which must be ignored.
+// The unsatisfiable "bytes=-0" is returned with a negative start.
+func parseRange(header string) (byteRange, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return byteRange{}, false
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return byteRange{}, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return byteRange{}, false
+		}
+		if suffix == 0 {
+			return byteRange{start: -1}, true
+		}
+		return byteRange{end: -1, suffix: suffix}, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false
+	}
+
+	end := int64(-1)
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return byteRange{}, false
+		}
+	}
+
+	return byteRange{start: start, end: end}, true
+}
+
+// limitWriter stops accepting data after n bytes.
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errLimitReached
+	}
+
+	truncated := int64(len(p)) > l.n
+	if truncated {
+		p = p[:l.n]
+	}
+
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	if truncated {
+		return n, errLimitReached
+	}
+
+	return n, nil
+}
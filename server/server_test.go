@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/birabittoh/miri"
+)
+
+const (
+	testSecretKey = "0123456789abcdef"
+	testSongID    = "3135556"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    byteRange
+		partial bool
+	}{
+		{"", byteRange{}, false},
+		{"bytes=0-", byteRange{start: 0, end: -1}, true},
+		{"bytes=100-199", byteRange{start: 100, end: 199}, true},
+		{"bytes=100-100", byteRange{start: 100, end: 100}, true},
+		{"bytes=-500", byteRange{end: -1, suffix: 500}, true},
+		{"bytes=-0", byteRange{start: -1}, true},
+		{"bytes=200-100", byteRange{}, false},
+		{"bytes=0-1,5-6", byteRange{}, false},
+		{"bytes=abc-", byteRange{}, false},
+		{"bytes=-", byteRange{}, false},
+		{"bytes=--5", byteRange{}, false},
+		{"bytes=5", byteRange{}, false},
+		{"items=0-5", byteRange{}, false},
+	}
+
+	for _, tt := range tests {
+		got, partial := parseRange(tt.header)
+		if got != tt.want || partial != tt.partial {
+			t.Errorf("parseRange(%q) = %+v, %v, want %+v, %v", tt.header, got, partial, tt.want, tt.partial)
+		}
+	}
+}
+
+// newTestHandler serves a track from a fake gateway, media API and CDN. The CDN serves
+// random bytes as the encrypted media, so the expected output is their decryption.
+func newTestHandler(t *testing.T, size int, knownSize bool) (http.Handler, []byte) {
+	t.Helper()
+
+	encrypted := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(encrypted)
+
+	var decrypted bytes.Buffer
+	if err := miri.DecryptFile(testSecretKey, testSongID, bytes.NewReader(encrypted), &decrypted); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/gw", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"error":[],"results":{"DATA":{"SNG_ID":%q,"SNG_TITLE":"Harder, Better, Faster, Stronger","ART_NAME":"Daft Punk","TRACK_TOKEN":"token"}}}`, testSongID)
+	})
+	mux.HandleFunc("/media", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": []any{map[string]any{"media": []any{map[string]any{
+			"media_type": "FULL",
+			"cipher":     map[string]string{"type": "BF_CBC_STRIPE"},
+			"format":     "MP3_128",
+			"sources":    []any{map[string]string{"url": srv.URL + "/cdn", "provider": "test"}},
+		}}}}})
+	})
+	mux.HandleFunc("/cdn", func(w http.ResponseWriter, r *http.Request) {
+		if knownSize {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(encrypted))
+			return
+		}
+
+		// a chunked response ignoring the Range header, so the size is unknown
+		w.Write(encrypted[:1])
+		w.(http.Flusher).Flush()
+		w.Write(encrypted[1:])
+	})
+
+	cfg := &miri.Config{
+		SecretKey:  testSecretKey,
+		Quality:    "mp3_128",
+		Timeout:    time.Minute,
+		GatewayURL: srv.URL + "/gw",
+		MediaURL:   srv.URL + "/media",
+	}
+	client, err := miri.NewFromSession(context.Background(), cfg, &miri.Session{APIToken: "token", HttpClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHandler(client), decrypted.Bytes()
+}
+
+func TestServeTrack(t *testing.T) {
+	const size = 10*2048 + 777
+	handler, track := newTestHandler(t, size, true)
+
+	tests := []struct {
+		name         string
+		method       string
+		rangeHeader  string
+		status       int
+		body         []byte
+		contentRange string
+	}{
+		{"whole track", "GET", "", http.StatusOK, track, ""},
+		{"head", "HEAD", "", http.StatusOK, nil, ""},
+		{"range", "GET", "bytes=100-4999", http.StatusPartialContent, track[100:5000], fmt.Sprintf("bytes 100-4999/%d", size)},
+		{"range in a later stripe", "GET", "bytes=6200-", http.StatusPartialContent, track[6200:], fmt.Sprintf("bytes 6200-%d/%d", size-1, size)},
+		{"end past the size", "GET", "bytes=20000-999999", http.StatusPartialContent, track[20000:], fmt.Sprintf("bytes 20000-%d/%d", size-1, size)},
+		{"suffix", "GET", "bytes=-500", http.StatusPartialContent, track[size-500:], fmt.Sprintf("bytes %d-%d/%d", size-500, size-1, size)},
+		{"suffix longer than the track", "GET", "bytes=-999999", http.StatusPartialContent, track, fmt.Sprintf("bytes 0-%d/%d", size-1, size)},
+		{"multiple ranges are ignored", "GET", "bytes=0-1,5-6", http.StatusOK, track, ""},
+		{"malformed ranges are ignored", "GET", "bytes=abc-", http.StatusOK, track, ""},
+		{"start past the end", "GET", fmt.Sprintf("bytes=%d-", size), http.StatusRequestedRangeNotSatisfiable, nil, fmt.Sprintf("bytes */%d", size)},
+		{"empty suffix", "GET", "bytes=-0", http.StatusRequestedRangeNotSatisfiable, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/tracks/"+testSongID, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("got Content-Range %q, want %q", got, tt.contentRange)
+			}
+			if tt.status == http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+			if !bytes.Equal(rec.Body.Bytes(), tt.body) {
+				t.Errorf("got %d bytes of body, want %d", rec.Body.Len(), len(tt.body))
+			}
+			if tt.method == "GET" && rec.Header().Get("Content-Length") != fmt.Sprint(len(tt.body)) {
+				t.Errorf("got Content-Length %s, want %d", rec.Header().Get("Content-Length"), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestServeTrackUnknownSize(t *testing.T) {
+	handler, track := newTestHandler(t, 5*2048+10, false)
+
+	for _, rangeHeader := range []string{"bytes=0-99", "bytes=3000-", "bytes=-100"} {
+		req := httptest.NewRequest("GET", "/tracks/"+testSongID, nil)
+		req.Header.Set("Range", rangeHeader)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d, want a full response", rangeHeader, rec.Code)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), track) {
+			t.Fatalf("%s: got %d bytes, want the whole track", rangeHeader, rec.Body.Len())
+		}
+	}
+}
+
+func TestServeTrackInvalidID(t *testing.T) {
+	handler, _ := newTestHandler(t, 2048, true)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/tracks/abc", nil))
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid track ID") {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+}
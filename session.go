@@ -3,43 +3,167 @@ package miri
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"time"
 )
 
+// ErrSessionExpired is returned when Deezer rejects the API or license token of a session.
+var ErrSessionExpired = errors.New("session expired")
+
+var deezerURL = &url.URL{Scheme: "https", Host: "www.deezer.com", Path: "/"}
+
 type UserDataResponse struct {
 	Results struct {
-		APIToken string `json:"checkForm"`
-		User     struct {
-			Id      int `json:"USER_ID"`
-			Options struct {
-				LicenseToken  string `json:"license_token"`
-				MobileOffline bool   `json:"mobile_offline"`
-				WebOffline    bool   `json:"web_offline"`
+		APIToken  string `json:"checkForm"`
+		Country   string `json:"COUNTRY"`
+		OfferName string `json:"OFFER_NAME"`
+		User      struct {
+			Id                    int      `json:"USER_ID"`
+			Name                  string   `json:"BLOG_NAME"`
+			ExplicitContentLevel  string   `json:"EXPLICIT_CONTENT_LEVEL"`
+			ExplicitContentLevels []string `json:"EXPLICIT_CONTENT_LEVELS_AVAILABLE"`
+			Options               struct {
+				LicenseToken        string `json:"license_token"`
+				LicenseCountry      string `json:"license_country"`
+				ExpirationTimestamp int64  `json:"expiration_timestamp"`
+				MobileOffline       bool   `json:"mobile_offline"`
+				WebOffline          bool   `json:"web_offline"`
+				WebHQ               bool   `json:"web_hq"`
+				MobileHQ            bool   `json:"mobile_hq"`
+				WebLossless         bool   `json:"web_lossless"`
+				MobileLossless      bool   `json:"mobile_lossless"`
+				CanStreamHQ         bool   `json:"can_stream_hq"`
+				CanStreamLossless   bool   `json:"can_stream_lossless"`
 			} `json:"OPTIONS"`
 		} `json:"USER"`
 	} `json:"results"`
 }
 
+// UserInfo describes the account behind a session.
+type UserInfo struct {
+	ID                    int       `json:"id"`
+	Name                  string    `json:"name"`
+	Country               string    `json:"country"`
+	Offer                 string    `json:"offer"` // subscription tier, e.g. "Deezer Premium"
+	CanStreamHQ           bool      `json:"can_stream_hq"`
+	CanStreamLossless     bool      `json:"can_stream_lossless"`
+	LicenseExpiry         time.Time `json:"license_expiry"`
+	ExplicitContentLevel  string    `json:"explicit_content_level"`
+	ExplicitContentLevels []string  `json:"explicit_content_levels"` // levels the user can choose from
+}
+
+// CanStream reports whether the account can stream the given quality ("mp3_128", "mp3_320" or "flac").
+func (u *UserInfo) CanStream(quality string) bool {
+	switch quality {
+	case "mp3_128":
+		return true
+	case "mp3_320":
+		return u.CanStreamHQ
+	case "flac":
+		return u.CanStreamLossless
+	default:
+		return false
+	}
+}
+
 type Session struct {
 	ArlCookie    string
 	APIToken     string
 	LicenseToken string
 	HttpClient   *http.Client
 	Premium      bool
+	Expiry       time.Time // expiry of the license token, zero if unknown
+	User         UserInfo
 }
 
-func authenticate(ctx context.Context, arlCookie string) (*Session, error) {
+// savedSession is the serialized form of a Session.
+type savedSession struct {
+	ArlCookie    string            `json:"arl_cookie"`
+	APIToken     string            `json:"api_token"`
+	LicenseToken string            `json:"license_token"`
+	Premium      bool              `json:"premium"`
+	Expiry       time.Time         `json:"expiry"`
+	Cookies      map[string]string `json:"cookies"`
+	User         UserInfo          `json:"user"`
+}
+
+// Expired reports whether the license token of the session is known to be expired.
+func (s *Session) Expired() bool {
+	return !s.Expiry.IsZero() && time.Now().After(s.Expiry)
+}
+
+// MarshalJSON serializes the session along with the deezer.com cookies of its HTTP client.
+func (s *Session) MarshalJSON() ([]byte, error) {
+	saved := savedSession{
+		ArlCookie:    s.ArlCookie,
+		APIToken:     s.APIToken,
+		LicenseToken: s.LicenseToken,
+		Premium:      s.Premium,
+		Expiry:       s.Expiry,
+		User:         s.User,
+	}
+
+	if s.HttpClient != nil && s.HttpClient.Jar != nil {
+		saved.Cookies = make(map[string]string)
+		for _, cookie := range s.HttpClient.Jar.Cookies(deezerURL) {
+			saved.Cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	return json.Marshal(saved)
+}
+
+// UnmarshalJSON restores a session serialized with MarshalJSON, with a new HTTP client holding its cookies.
+func (s *Session) UnmarshalJSON(data []byte) error {
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	client, err := newSessionClient()
+	if err != nil {
+		return err
+	}
+	cookies := make([]*http.Cookie, 0, len(saved.Cookies))
+	for name, value := range saved.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+	}
+	client.Jar.SetCookies(deezerURL, cookies)
+
+	*s = Session{
+		ArlCookie:    saved.ArlCookie,
+		APIToken:     saved.APIToken,
+		LicenseToken: saved.LicenseToken,
+		HttpClient:   client,
+		Premium:      saved.Premium,
+		Expiry:       saved.Expiry,
+		User:         saved.User,
+	}
+
+	return nil
+}
+
+func newSessionClient() (*http.Client, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
-	client := &http.Client{
+
+	return &http.Client{
 		Timeout: 20 * time.Second,
 		Jar:     jar,
+	}, nil
+}
+
+func authenticate(ctx context.Context, arlCookie string) (*Session, error) {
+	client, err := newSessionClient()
+	if err != nil {
+		return nil, err
 	}
 
 	url := "https://www.deezer.com/ajax/gw-light.php?method=deezer.getUserData&input=3&api_version=1.0&api_token="
@@ -77,7 +201,18 @@ func authenticate(ctx context.Context, arlCookie string) (*Session, error) {
 		return nil, fmt.Errorf("invalid arl cookie")
 	}
 
-	isPremium := res.Results.User.Options.MobileOffline || res.Results.User.Options.WebOffline
+	user := res.Results.User
+	isPremium := user.Options.MobileOffline || user.Options.WebOffline
+
+	var expiry time.Time
+	if ts := user.Options.ExpirationTimestamp; ts > 0 {
+		expiry = time.Unix(ts, 0)
+	}
+
+	country := user.Options.LicenseCountry
+	if country == "" {
+		country = res.Results.Country
+	}
 
 	return &Session{
 		ArlCookie:    arlCookie,
@@ -85,5 +220,17 @@ func authenticate(ctx context.Context, arlCookie string) (*Session, error) {
 		LicenseToken: res.Results.User.Options.LicenseToken,
 		HttpClient:   client,
 		Premium:      isPremium,
+		Expiry:       expiry,
+		User: UserInfo{
+			ID:                    user.Id,
+			Name:                  user.Name,
+			Country:               country,
+			Offer:                 res.Results.OfferName,
+			CanStreamHQ:           user.Options.CanStreamHQ || user.Options.WebHQ || user.Options.MobileHQ,
+			CanStreamLossless:     user.Options.CanStreamLossless || user.Options.WebLossless || user.Options.MobileLossless,
+			LicenseExpiry:         expiry,
+			ExplicitContentLevel:  user.ExplicitContentLevel,
+			ExplicitContentLevels: user.ExplicitContentLevels,
+		},
 	}, nil
 }
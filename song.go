@@ -3,6 +3,7 @@ package miri
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type Contributors struct {
@@ -30,11 +31,70 @@ type Song struct {
 	Version      string       `json:"VERSION"`
 	Cover        string       `json:"ALB_PICTURE"`
 	Contributors Contributors `json:"SNG_CONTRIBUTORS"`
-	Duration     string       `json:"DURATION"`
-	Gain         string       `json:"GAIN"`
 	ISRC         string       `json:"ISRC"`
-	TrackNumber  string       `json:"TRACK_NUMBER"`
 	TrackToken   string       `json:"TRACK_TOKEN"`
+
+	// Deprecated: use DurationSeconds.
+	Duration string `json:"DURATION"`
+	// Deprecated: use ReplayGain.
+	Gain string `json:"GAIN"`
+	// Deprecated: use Position.
+	TrackNumber string `json:"TRACK_NUMBER"`
+
+	DurationSeconds int     `json:"-"`
+	ReplayGain      float64 `json:"-"` // decibels
+	Position        int     `json:"-"` // position of the song in its album
+
+	// TrackTokenExpire is the Unix time at which TrackToken stops being accepted, or zero if unknown.
+	TrackTokenExpire int64 `json:"TRACK_TOKEN_EXPIRE"`
+
+	ArtistID        string    `json:"ART_ID"`
+	AlbumID         string    `json:"ALB_ID"`
+	AlbumTitle      string    `json:"ALB_TITLE"`
+	DiskNumber      int       `json:"DISK_NUMBER"`
+	ExplicitLyrics  bool      `json:"EXPLICIT_LYRICS"`
+	BPM             float64   `json:"BPM"`
+	PhysicalRelease time.Time `json:"PHYSICAL_RELEASE_DATE"`
+
+	// Episode is set when the song stands for a podcast episode, see EpisodeData.Song.
+	Episode *EpisodeData `json:"-"`
+}
+
+func (s *Song) UnmarshalJSON(data []byte) error {
+	type Alias Song
+	aux := struct {
+		*Alias
+		Duration         flexString `json:"DURATION"`
+		Gain             flexString `json:"GAIN"`
+		TrackNumber      flexString `json:"TRACK_NUMBER"`
+		TrackTokenExpire flexInt    `json:"TRACK_TOKEN_EXPIRE"`
+		DiskNumber       flexInt    `json:"DISK_NUMBER"`
+		ExplicitLyrics   flexBool   `json:"EXPLICIT_LYRICS"`
+		BPM              flexFloat  `json:"BPM"`
+		PhysicalRelease  flexDate   `json:"PHYSICAL_RELEASE_DATE"`
+	}{Alias: (*Alias)(s)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	s.Duration = string(aux.Duration)
+	s.Gain = string(aux.Gain)
+	s.TrackNumber = string(aux.TrackNumber)
+
+	// the textual forms are kept, so a value that is not a number is only lost in the typed field
+	duration, _ := parseNumber(s.Duration)
+	s.DurationSeconds = int(duration)
+	s.ReplayGain, _ = parseNumber(s.Gain)
+	position, _ := parseNumber(s.TrackNumber)
+	s.Position = int(position)
+	s.TrackTokenExpire = int64(aux.TrackTokenExpire)
+	s.DiskNumber = int(aux.DiskNumber)
+	s.ExplicitLyrics = bool(aux.ExplicitLyrics)
+	s.BPM = float64(aux.BPM)
+	s.PhysicalRelease = time.Time(aux.PhysicalRelease)
+
+	return nil
 }
 
 func (s *Song) GetTitle() string {
@@ -45,3 +105,18 @@ func (s *Song) GetTitle() string {
 
 	return songTitle
 }
+
+// DurationTime returns the duration of the song, or zero if unknown.
+func (s *Song) DurationTime() time.Duration {
+	return time.Duration(s.DurationSeconds) * time.Second
+}
+
+// GainDB returns the replay gain of the song in decibels, or zero if unknown.
+func (s *Song) GainDB() float64 {
+	return s.ReplayGain
+}
+
+// Number returns the position of the song in its album, or zero if unknown.
+func (s *Song) Number() int {
+	return s.Position
+}
@@ -0,0 +1,133 @@
+package miri
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readSong(t *testing.T, name string) *Song {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var song Song
+	if err := json.Unmarshal(data, &song); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+
+	return &song
+}
+
+func TestSongUnmarshal(t *testing.T) {
+	tests := []struct {
+		file        string
+		title       string
+		duration    time.Duration
+		gain        float64
+		number      int
+		disk        int
+		explicit    bool
+		bpm         float64
+		released    string
+		tokenExpire int64
+	}{
+		{
+			file:        "song_quoted.json",
+			title:       "Harder, Better, Faster, Stronger",
+			duration:    224 * time.Second,
+			gain:        -12.4,
+			number:      4,
+			disk:        1,
+			bpm:         123.7,
+			released:    "2001-03-07",
+			tokenExpire: 1760000000,
+		},
+		{
+			file:        "song_unquoted.json",
+			title:       "Lose Yourself (Soundtrack Version)",
+			duration:    326 * time.Second,
+			gain:        -9,
+			number:      11,
+			disk:        1,
+			explicit:    true,
+			bpm:         171,
+			released:    "2005-12-06",
+			tokenExpire: 1760000000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			s := readSong(t, tt.file)
+
+			if got := s.GetTitle(); got != tt.title {
+				t.Errorf("GetTitle() = %q, want %q", got, tt.title)
+			}
+			if got := s.DurationTime(); got != tt.duration {
+				t.Errorf("DurationTime() = %v, want %v", got, tt.duration)
+			}
+			if got := s.GainDB(); got != tt.gain {
+				t.Errorf("GainDB() = %v, want %v", got, tt.gain)
+			}
+			if got := s.Number(); got != tt.number {
+				t.Errorf("Number() = %d, want %d", got, tt.number)
+			}
+			if s.DiskNumber != tt.disk {
+				t.Errorf("DiskNumber = %d, want %d", s.DiskNumber, tt.disk)
+			}
+			if s.ExplicitLyrics != tt.explicit {
+				t.Errorf("ExplicitLyrics = %v, want %v", s.ExplicitLyrics, tt.explicit)
+			}
+			if s.BPM != tt.bpm {
+				t.Errorf("BPM = %v, want %v", s.BPM, tt.bpm)
+			}
+			if got := s.PhysicalRelease.Format(time.DateOnly); got != tt.released {
+				t.Errorf("PhysicalRelease = %s, want %s", got, tt.released)
+			}
+			if s.TrackTokenExpire != tt.tokenExpire {
+				t.Errorf("TrackTokenExpire = %d, want %d", s.TrackTokenExpire, tt.tokenExpire)
+			}
+		})
+	}
+}
+
+// TestSongRoundTrip checks that songs survive the metadata cache, which stores them re-marshalled.
+func TestSongRoundTrip(t *testing.T) {
+	for _, file := range []string{"song_quoted.json", "song_unquoted.json"} {
+		s := readSong(t, file)
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded Song
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: failed to decode marshalled song: %v", file, err)
+		}
+		if decoded.DurationTime() != s.DurationTime() || decoded.Number() != s.Number() ||
+			!decoded.PhysicalRelease.Equal(s.PhysicalRelease) || decoded.ExplicitLyrics != s.ExplicitLyrics {
+			t.Errorf("%s: song changed through a marshalling round trip", file)
+		}
+	}
+}
+
+func TestSongUnparsable(t *testing.T) {
+	var s Song
+	if err := json.Unmarshal([]byte(`{"DURATION":"","GAIN":"n/a","TRACK_NUMBER":"A1"}`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.DurationTime() != 0 || s.GainDB() != 0 || s.Number() != 0 {
+		t.Fatal("accessors of unparsable values must return zero")
+	}
+	if s.Gain != "n/a" || s.TrackNumber != "A1" {
+		t.Fatalf("textual forms not kept: %q, %q", s.Gain, s.TrackNumber)
+	}
+}
@@ -0,0 +1,280 @@
+package miri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SyncManifestName is the name of the manifest SyncPlaylist keeps in the target directory.
+const SyncManifestName = ".miri-sync.json"
+
+// SyncOptions configures SyncPlaylist.
+type SyncOptions struct {
+	// Dir is the directory the songs are downloaded to.
+	Dir string
+	// Prune deletes the files of songs that were removed from the playlist.
+	Prune bool
+	// Upgrade downloads again songs stored in a lower format than the configured quality,
+	// when a better one has become available.
+	Upgrade bool
+}
+
+// SyncEntry is a song stored by SyncPlaylist.
+type SyncEntry struct {
+	ID     string `json:"id"`
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// SyncManifest records the songs of a synced playlist. Paths are relative to the synced directory.
+type SyncManifest struct {
+	PlaylistID int                   `json:"playlist_id"`
+	Songs      map[string]*SyncEntry `json:"songs"`
+}
+
+// SyncReport describes the changes made by SyncPlaylist.
+type SyncReport struct {
+	Added     []*SyncEntry
+	Upgraded  []*SyncEntry
+	Removed   []*SyncEntry
+	Unchanged int
+	Failed    map[string]error
+}
+
+// SyncPlaylist downloads the songs of a playlist that are missing from dir,
+// comparing the playlist with the manifest saved by the previous run.
+// Songs that fail to download are reported in SyncReport.Failed and retried on the next run.
+func (c *Client) SyncPlaylist(ctx context.Context, playlistID int, opt SyncOptions) (*SyncReport, error) {
+	playlist := &Playlist{}
+	if err := c.FetchResource(ctx, playlist, playlistID); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(opt.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	manifest, err := readSyncManifest(opt.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.PlaylistID != 0 && manifest.PlaylistID != playlistID {
+		return nil, fmt.Errorf("%s belongs to playlist %d", filepath.Join(opt.Dir, SyncManifestName), manifest.PlaylistID)
+	}
+	manifest.PlaylistID = playlistID
+
+	report := &SyncReport{Failed: map[string]error{}}
+	current := map[string]bool{}
+
+	for _, song := range playlist.GetSongs() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		current[song.ID] = true
+
+		entry, ok := manifest.Songs[song.ID]
+		if ok && fileExists(filepath.Join(opt.Dir, entry.Path)) {
+			upgrade, err := c.canUpgrade(ctx, song, entry, opt)
+			if err != nil {
+				report.Failed[song.ID] = err
+				continue
+			}
+			if !upgrade {
+				report.Unchanged++
+				continue
+			}
+		} else {
+			entry = nil
+		}
+
+		synced, err := c.SaveSong(ctx, song, opt.Dir, manifest)
+		if err != nil {
+			report.Failed[song.ID] = err
+			continue
+		}
+
+		manifest.Songs[song.ID] = synced
+		if entry != nil {
+			if manifest.owner(entry.Path) == "" {
+				os.Remove(filepath.Join(opt.Dir, entry.Path))
+			}
+			report.Upgraded = append(report.Upgraded, synced)
+		} else {
+			report.Added = append(report.Added, synced)
+		}
+
+		if err := writeSyncManifest(opt.Dir, manifest); err != nil {
+			return report, err
+		}
+	}
+
+	if opt.Prune {
+		for id, entry := range manifest.Songs {
+			if current[id] {
+				continue
+			}
+
+			delete(manifest.Songs, id)
+
+			// manifests written before paths were disambiguated may share a file between songs
+			if manifest.owner(entry.Path) == "" {
+				err := os.Remove(filepath.Join(opt.Dir, entry.Path))
+				if err != nil && !errors.Is(err, fs.ErrNotExist) {
+					manifest.Songs[id] = entry
+					report.Failed[id] = err
+					continue
+				}
+			}
+
+			report.Removed = append(report.Removed, entry)
+		}
+	}
+
+	return report, writeSyncManifest(opt.Dir, manifest)
+}
+
+// canUpgrade reports whether a better format than the stored one is available for song.
+func (c *Client) canUpgrade(ctx context.Context, song *Song, entry *SyncEntry, opt SyncOptions) (bool, error) {
+	if !opt.Upgrade || formatRank(entry.Format) >= formatRank(c.appConfig.Quality) {
+		return false, nil
+	}
+
+	media, err := c.GetMedia(ctx, song)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch media: %w", err)
+	}
+
+	return formatRank(media.GetFormat()) > formatRank(entry.Format), nil
+}
+
+// SaveSong downloads a song to a temporary file in dir, then renames it to "Artist - Title" once the format is known.
+// Files are never overwritten unless manifest, which may be nil, records them as the song's:
+// on a clash, the song ID is added to the name.
+func (c *Client) SaveSong(ctx context.Context, song *Song, dir string, manifest *SyncManifest) (*SyncEntry, error) {
+	f, err := os.CreateTemp(dir, ".miri-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	res, err := c.StreamSong(ctx, song, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	name, err := manifest.songPath(dir, song, res.Format)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+		return nil, err
+	}
+
+	return &SyncEntry{ID: song.ID, Format: res.Format, Path: name, SHA256: res.SHA256}, nil
+}
+
+// songPath returns a file name for song that no other file uses, adding the song ID
+// on a clash, such as the same song appearing on several albums.
+func (m *SyncManifest) songPath(dir string, song *Song, format string) (string, error) {
+	base := SanitizeFilename(fmt.Sprintf("%s - %s", song.Artist, song.GetTitle()))
+	ext := FormatExtension(format)
+
+	for _, name := range []string{base + ext, fmt.Sprintf("%s [%s]%s", base, song.ID, ext)} {
+		owner := m.owner(name)
+		if owner == song.ID || owner == "" && !fileExists(filepath.Join(dir, name)) {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no free file name for song %s in %s", song.ID, dir)
+}
+
+// owner returns the ID of the song stored at path, or an empty string.
+func (m *SyncManifest) owner(path string) string {
+	if m == nil {
+		return ""
+	}
+
+	for id, entry := range m.Songs {
+		if entry.Path == path {
+			return id
+		}
+	}
+
+	return ""
+}
+
+func readSyncManifest(dir string) (*SyncManifest, error) {
+	manifest := &SyncManifest{}
+
+	data, err := os.ReadFile(filepath.Join(dir, SyncManifestName))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, fmt.Errorf("invalid sync manifest: %w", err)
+		}
+	}
+
+	if manifest.Songs == nil {
+		manifest.Songs = map[string]*SyncEntry{}
+	}
+
+	return manifest, nil
+}
+
+// writeSyncManifest replaces the manifest atomically, so an interrupted sync never leaves it truncated.
+func writeSyncManifest(dir string, manifest *SyncManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".miri-sync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, SyncManifestName))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func formatRank(format string) int {
+	switch strings.ToUpper(format) {
+	case "MP3_128":
+		return 1
+	case "MP3_320":
+		return 2
+	case "FLAC":
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,124 @@
+package miri
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncSongPath(t *testing.T) {
+	dir := t.TempDir()
+	m := &SyncManifest{Songs: map[string]*SyncEntry{
+		"1": {ID: "1", Format: "MP3_320", Path: "Daft Punk - One More Time.mp3"},
+	}}
+
+	tests := []struct {
+		id     string
+		title  string
+		format string
+		want   string
+	}{
+		{"1", "One More Time", "MP3_320", "Daft Punk - One More Time.mp3"},
+		{"1", "One More Time", "FLAC", "Daft Punk - One More Time.flac"},
+		{"2", "One More Time", "MP3_320", "Daft Punk - One More Time [2].mp3"},
+		{"3", "Aerodynamic", "MP3_320", "Daft Punk - Aerodynamic [3].mp3"},
+		{"4", "Digital Love: Live", "MP3_128", "Daft Punk - Digital Love_ Live.mp3"},
+	}
+
+	// a file that is not in the manifest must not be overwritten
+	if err := os.WriteFile(filepath.Join(dir, "Daft Punk - Aerodynamic.mp3"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		song := &Song{ID: tt.id, Artist: "Daft Punk", Title: tt.title}
+
+		got, err := m.songPath(dir, song, tt.format)
+		if err != nil {
+			t.Fatalf("song %s: %v", tt.id, err)
+		}
+		if got != tt.want {
+			t.Errorf("song %s: got %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestSyncManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := readSyncManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.PlaylistID = 908622995
+	m.Songs["1"] = &SyncEntry{ID: "1", Format: "FLAC", Path: "a.flac"}
+
+	if err := writeSyncManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readSyncManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.PlaylistID != m.PlaylistID || read.Songs["1"].Path != "a.flac" || read.owner("a.flac") != "1" {
+		t.Fatalf("manifest changed through a round trip: %+v", read)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want only the manifest", len(files))
+	}
+}
+
+func TestSaveSongKeepsExistingFiles(t *testing.T) {
+	c, _ := newGatewayClient(t, func(call gatewayCall) (any, any) {
+		return nil, nil
+	})
+	c.appConfig.Quality = "mp3_320"
+
+	var err error
+	c.cache, err = newDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := c.cache.open(context.Background(), cacheKey("42", "MP3_320"), func(w io.Writer) error {
+		_, err := io.WriteString(w, "audio")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Daft Punk - Veridis Quo.mp3"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	song := &Song{ID: "42", Artist: "Daft Punk", Title: "Veridis Quo"}
+	entry, err := c.SaveSong(context.Background(), song, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Path != "Daft Punk - Veridis Quo [42].mp3" {
+		t.Fatalf("got %q, want the name with the song ID", entry.Path)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Daft Punk - Veridis Quo.mp3"))
+	if err != nil || string(data) != "mine" {
+		t.Fatalf("existing file was overwritten: %q, %v", data, err)
+	}
+
+	// without a manifest, both names are now taken
+	if _, err := c.SaveSong(context.Background(), song, dir, nil); err == nil {
+		t.Fatal("expected an error when no file name is free")
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2: temporary files must be removed", len(files))
+	}
+}
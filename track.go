@@ -3,7 +3,6 @@ package miri
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 )
 
 type Track struct {
@@ -18,10 +17,8 @@ func (t *Track) String() string {
 	}
 
 	duration := "Unknown"
-	if t.Results.Data.Duration != "" {
-		if d, err := time.ParseDuration(t.Results.Data.Duration + "s"); err == nil {
-			duration = d.String()
-		}
+	if d := t.Results.Data.DurationTime(); d > 0 {
+		duration = d.String()
 	}
 
 	return fmt.Sprintf(
@@ -0,0 +1,138 @@
+package miri
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxRedirects = 10
+
+var (
+	localePattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+
+	urlKinds = map[string]bool{
+		"track":    true,
+		"album":    true,
+		"playlist": true,
+		"artist":   true,
+		"episode":  true,
+		"show":     true,
+	}
+
+	shortLinkHosts = map[string]bool{
+		"deezer.page.link": true,
+		"link.deezer.com":  true,
+	}
+)
+
+// URLParser extracts resources from deezer.com URLs, following short links if needed.
+type URLParser struct {
+	HTTPClient *http.Client // used to follow short links, defaults to http.DefaultClient
+}
+
+// ParseURL returns the kind and ID of the resource pointed to by a deezer.com
+// or deezer.page.link URL, using the default URLParser.
+func ParseURL(ctx context.Context, rawURL string) (kind string, id int, err error) {
+	return (&URLParser{}).Parse(ctx, rawURL)
+}
+
+// ParseResourceURL parses a URL like ParseURL and returns an empty resource of the right type,
+// ready to be fetched with Client.FetchResource.
+func ParseResourceURL(ctx context.Context, rawURL string) (Resource, int, error) {
+	return (&URLParser{}).ParseResource(ctx, rawURL)
+}
+
+// ParseResource parses rawURL and returns an empty resource of the right type and its ID.
+func (p *URLParser) ParseResource(ctx context.Context, rawURL string) (Resource, int, error) {
+	kind, id, err := p.Parse(ctx, rawURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resource, err := NewResource(kind)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resource, id, nil
+}
+
+// Parse returns the kind and ID of the resource pointed to by rawURL.
+// Kinds are "track", "album", "playlist", "artist", "episode" and "show".
+func (p *URLParser) Parse(ctx context.Context, rawURL string) (kind string, id int, err error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if shortLinkHosts[u.Hostname()] {
+		u, err = p.resolve(ctx, u)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to resolve short link: %w", err)
+		}
+	}
+
+	host := u.Hostname()
+	if host != "deezer.com" && !strings.HasSuffix(host, ".deezer.com") {
+		return "", 0, fmt.Errorf("not a deezer.com URL: %s", rawURL)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 0 && localePattern.MatchString(parts[0]) {
+		parts = parts[1:]
+	}
+
+	if len(parts) < 2 || !urlKinds[parts[0]] {
+		return "", 0, fmt.Errorf("unsupported URL: %s", rawURL)
+	}
+
+	id, err = strconv.Atoi(parts[1])
+	if err != nil || id <= 0 {
+		return "", 0, fmt.Errorf("invalid %s ID: %s", parts[0], parts[1])
+	}
+
+	return parts[0], id, nil
+}
+
+// resolve follows redirects until it reaches a deezer.com URL.
+func (p *URLParser) resolve(ctx context.Context, u *url.URL) (*url.URL, error) {
+	client := http.DefaultClient
+	if p.HTTPClient != nil {
+		client = p.HTTPClient
+	}
+
+	noFollow := *client
+	noFollow.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for range maxRedirects {
+		if !shortLinkHosts[u.Hostname()] {
+			return u, nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := noFollow.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		if err != nil {
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		u = location
+	}
+
+	return nil, fmt.Errorf("too many redirects")
+}
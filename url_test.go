@@ -0,0 +1,88 @@
+package miri
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// redirectTransport answers every request with a redirect to the location set for its URL.
+type redirectTransport map[string]string
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	if location, ok := rt[req.URL.String()]; ok {
+		http.Redirect(rec, req, location, http.StatusFound)
+	} else {
+		http.NotFound(rec, req)
+	}
+
+	return rec.Result(), nil
+}
+
+func TestParseURL(t *testing.T) {
+	parser := &URLParser{HTTPClient: &http.Client{Transport: redirectTransport{
+		"https://deezer.page.link/abc":    "https://link.deezer.com/s/xyz",
+		"https://link.deezer.com/s/xyz":   "https://www.deezer.com/fr/album/302127?utm_source=share",
+		"https://deezer.page.link/direct": "https://www.deezer.com/playlist/908622995",
+		"https://deezer.page.link/loop":   "https://link.deezer.com/s/loop",
+		"https://link.deezer.com/s/loop":  "https://deezer.page.link/loop",
+		"https://deezer.page.link/away":   "https://example.com/track/1",
+	}}}
+
+	tests := []struct {
+		url     string
+		kind    string
+		id      int
+		wantErr bool
+	}{
+		{url: "https://www.deezer.com/track/3135556", kind: "track", id: 3135556},
+		{url: "https://www.deezer.com/en/track/3135556", kind: "track", id: 3135556},
+		{url: "https://www.deezer.com/pt-br/album/302127", kind: "album", id: 302127},
+		{url: "https://deezer.com/playlist/908622995/", kind: "playlist", id: 908622995},
+		{url: "  https://www.deezer.com/us/artist/27?app_id=1  ", kind: "artist", id: 27},
+		{url: "https://www.deezer.com/en/episode/526673645", kind: "episode", id: 526673645},
+		{url: "https://www.deezer.com/show/1000442", kind: "show", id: 1000442},
+		{url: "https://deezer.page.link/abc", kind: "album", id: 302127},
+		{url: "https://deezer.page.link/direct", kind: "playlist", id: 908622995},
+		{url: "https://www.deezer.com/en/track/abc", wantErr: true},
+		{url: "https://www.deezer.com/en/track/0", wantErr: true},
+		{url: "https://www.deezer.com/en/track/-5", wantErr: true},
+		{url: "https://www.deezer.com/en/track", wantErr: true},
+		{url: "https://www.deezer.com/en/profile/123", wantErr: true},
+		{url: "https://www.deezer.com/english/track/1", wantErr: true},
+		{url: "https://notdeezer.com/track/1", wantErr: true},
+		{url: "https://example.com/track/1", wantErr: true},
+		{url: "https://deezer.page.link/unknown", wantErr: true},
+		{url: "https://deezer.page.link/loop", wantErr: true},
+		{url: "https://deezer.page.link/away", wantErr: true},
+		{url: "://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		kind, id, err := parser.Parse(t.Context(), tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %s %d, want an error", tt.url, kind, id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.url, err)
+			continue
+		}
+		if kind != tt.kind || id != tt.id {
+			t.Errorf("%q: got %s %d, want %s %d", tt.url, kind, id, tt.kind, tt.id)
+		}
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	resource, id, err := (&URLParser{}).ParseResource(t.Context(), "https://www.deezer.com/en/show/1000442")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resource.(*Show); !ok || id != 1000442 {
+		t.Fatalf("got %T %d, want *Show 1000442", resource, id)
+	}
+}
@@ -0,0 +1,122 @@
+package miri
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// flacHeaderSize is the size of the "fLaC" marker followed by the STREAMINFO block.
+const flacHeaderSize = 4 + 4 + 34
+
+var (
+	// ErrIncompleteDownload is returned when the stream ends before Content-Length bytes were received.
+	ErrIncompleteDownload = errors.New("incomplete download")
+	// ErrCorruptedMedia is returned when the downloaded media does not have a valid header.
+	ErrCorruptedMedia = errors.New("corrupted media")
+)
+
+// DownloadResult describes a completed download.
+type DownloadResult struct {
+	Song   *Song
+	Format string
+	Size   int64  // number of decrypted bytes written
+	SHA256 string // hex-encoded SHA-256 of the decrypted output
+	MD5    string // hex-encoded MD5 of the decrypted output
+
+	// FLACStreamMD5 is the hex-encoded MD5 of the decoded audio, as stored in the
+	// STREAMINFO block of FLAC files. It is empty for other formats or when the encoder did not set it.
+	// FLAC downloads are decoded to check it, along with the CRCs of their frames.
+	FLACStreamMD5 string
+}
+
+// downloadVerifier hashes and counts everything written to it,
+// keeping the first bytes around to check the media header.
+type downloadVerifier struct {
+	sha256 hash.Hash
+	md5    hash.Hash
+	header []byte
+	flac   flacVerifier
+	n      int64
+}
+
+func newDownloadVerifier() *downloadVerifier {
+	return &downloadVerifier{
+		sha256: sha256.New(),
+		md5:    md5.New(),
+		header: make([]byte, 0, flacHeaderSize),
+	}
+}
+
+func (v *downloadVerifier) Write(p []byte) (int, error) {
+	v.sha256.Write(p)
+	v.md5.Write(p)
+	v.flac.Write(p)
+	v.n += int64(len(p))
+
+	if missing := cap(v.header) - len(v.header); missing > 0 {
+		v.header = append(v.header, p[:min(missing, len(p))]...)
+	}
+
+	return len(p), nil
+}
+
+// result checks the download against the expected size (-1 if unknown) and the format header.
+func (v *downloadVerifier) result(song *Song, format string, expectedSize int64) (*DownloadResult, error) {
+	if expectedSize >= 0 && v.n != expectedSize {
+		return nil, fmt.Errorf("%w: got %d of %d bytes", ErrIncompleteDownload, v.n, expectedSize)
+	}
+
+	res := &DownloadResult{
+		Song:   song,
+		Format: format,
+		Size:   v.n,
+		SHA256: hex.EncodeToString(v.sha256.Sum(nil)),
+		MD5:    hex.EncodeToString(v.md5.Sum(nil)),
+	}
+
+	if format == "FLAC" {
+		streamMD5, err := parseFLACStreamMD5(v.header)
+		if err != nil {
+			return nil, err
+		}
+		res.FLACStreamMD5 = streamMD5
+
+		if err := v.flac.finish(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// parseFLACStreamMD5 validates the FLAC marker and STREAMINFO block and returns the audio MD5 it carries.
+func parseFLACStreamMD5(header []byte) (string, error) {
+	if len(header) < flacHeaderSize || !bytes.Equal(header[:4], []byte("fLaC")) {
+		return "", fmt.Errorf("%w: missing FLAC marker", ErrCorruptedMedia)
+	}
+
+	blockType := header[4] & 0x7f
+	blockLength := binary.BigEndian.Uint32(header[4:8]) & 0xffffff
+	if blockType != 0 || blockLength != 34 {
+		return "", fmt.Errorf("%w: missing FLAC STREAMINFO block", ErrCorruptedMedia)
+	}
+
+	streamInfo := header[8:flacHeaderSize]
+	totalSamples := binary.BigEndian.Uint64(streamInfo[10:18]) & 0xfffffffff
+	if totalSamples == 0 {
+		return "", nil
+	}
+
+	sum := streamInfo[18:34]
+	if bytes.Equal(sum, make([]byte, md5.Size)) {
+		return "", nil
+	}
+
+	return hex.EncodeToString(sum), nil
+}
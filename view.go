@@ -0,0 +1,149 @@
+package miri
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	imageURLFormat = "https://e-cdns-images.dzcdn.net/images/%s/%s/%dx%d-000000-80-0-0.jpg"
+	viewImageSize  = 1000
+)
+
+// ResourceView is a flat, stable representation of a resource, meant to be serialized to JSON.
+type ResourceView struct {
+	Type        string     `json:"type"` // "track", "album", "playlist", "artist", "episode" or "show"
+	ID          string     `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Artist      string     `json:"artist,omitempty"`
+	Creator     string     `json:"creator,omitempty"`
+	ReleaseDate string     `json:"release_date,omitempty"` // YYYY-MM-DD
+	Duration    int        `json:"duration"`               // in seconds
+	CoverURL    string     `json:"cover_url,omitempty"`
+	Songs       []SongView `json:"songs"`
+}
+
+// SongView is the representation of a song inside a ResourceView.
+type SongView struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Album       string `json:"album,omitempty"`
+	ISRC        string `json:"isrc,omitempty"`
+	Duration    int    `json:"duration"` // in seconds
+	TrackNumber int    `json:"track_number,omitempty"`
+	DiskNumber  int    `json:"disk_number,omitempty"`
+	Explicit    bool   `json:"explicit"`
+	CoverURL    string `json:"cover_url,omitempty"`
+}
+
+// NewResourceView converts a resource into its export representation.
+func NewResourceView(resource Resource) *ResourceView {
+	songs := resource.GetSongs()
+	v := &ResourceView{
+		Title: resource.GetTitle(),
+		Songs: make([]SongView, len(songs)),
+	}
+
+	var total time.Duration
+	for i, s := range songs {
+		v.Songs[i] = NewSongView(s)
+		total += s.DurationTime()
+	}
+	v.Duration = seconds(total)
+
+	switch r := resource.(type) {
+	case *Track:
+		v.Type = "track"
+		if s := r.Results.Data; s != nil {
+			v.ID = s.ID
+			v.Artist = s.Artist
+			v.CoverURL = s.CoverURL(viewImageSize)
+			if !s.PhysicalRelease.IsZero() {
+				v.ReleaseDate = s.PhysicalRelease.Format(time.DateOnly)
+			}
+		}
+	case *Album:
+		d := &r.Results.Data
+		v.Type = "album"
+		v.ID = d.ID
+		v.Artist = d.Artist
+		v.CoverURL = imageURL("cover", d.Cover, viewImageSize)
+		if date := d.ReleaseDate(); !date.IsZero() {
+			v.ReleaseDate = date.Format(time.DateOnly)
+		}
+		if d := d.DurationTime(); d > 0 {
+			v.Duration = seconds(d)
+		}
+	case *Playlist:
+		d := &r.Results.Data
+		v.Type = "playlist"
+		v.ID = d.ID
+		v.Creator = d.Creator
+		v.CoverURL = imageURL("playlist", d.Picture, viewImageSize)
+		if d.Duration > 0 {
+			v.Duration = d.Duration
+		}
+	case *Artist:
+		d := &r.Results.Data
+		v.Type = "artist"
+		v.ID = d.ID
+		v.Artist = d.Name
+		v.CoverURL = imageURL("artist", d.Picture, viewImageSize)
+	case *Episode:
+		v.Type = "episode"
+		if e := r.Results.Data; e != nil {
+			v.ID = e.ID
+			v.Artist = e.ShowName
+			v.CoverURL = imageURL("talk", e.Picture, viewImageSize)
+			if !e.PublishedAt.IsZero() {
+				v.ReleaseDate = e.PublishedAt.Format(time.DateOnly)
+			}
+		}
+	case *Show:
+		d := &r.Results.Data
+		v.Type = "show"
+		v.ID = d.ID
+		v.CoverURL = imageURL("talk", d.Picture, viewImageSize)
+	}
+
+	return v
+}
+
+// NewSongView converts a song into its export representation.
+func NewSongView(s *Song) SongView {
+	return SongView{
+		ID:          s.ID,
+		Title:       s.GetTitle(),
+		Artist:      s.Artist,
+		Album:       s.AlbumTitle,
+		ISRC:        s.ISRC,
+		Duration:    seconds(s.DurationTime()),
+		TrackNumber: s.Number(),
+		DiskNumber:  s.DiskNumber,
+		Explicit:    s.ExplicitLyrics,
+		CoverURL:    s.CoverURL(viewImageSize),
+	}
+}
+
+// CoverURL returns the URL of the album cover of the song, or of the show of an episode,
+// size pixels wide, or an empty string if it has none.
+func (s *Song) CoverURL(size int) string {
+	if s.Episode != nil {
+		return imageURL("talk", s.Cover, size)
+	}
+	return imageURL("cover", s.Cover, size)
+}
+
+func imageURL(kind, hash string, size int) string {
+	if hash == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(imageURLFormat, kind, hash, size, size)
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Round(d.Seconds()))
+}
@@ -0,0 +1,52 @@
+package miri
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestNewResourceViewGolden(t *testing.T) {
+	for _, kind := range []string{"track", "album", "playlist", "artist"} {
+		t.Run(kind, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", kind+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resource, err := NewResource(kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := resource.Unmarshal(data); err != nil {
+				t.Fatalf("failed to decode payload: %v", err)
+			}
+
+			got, err := json.MarshalIndent(NewResourceView(resource), "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", kind+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("view does not match %s, run with -update if the change is intended:\n%s", golden, got)
+			}
+		})
+	}
+}